@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// AppRoleAuth represents a form of authentication that takes a role ID and secret ID. It is intended for machines and
+// automated workflows (i.e. CI runners) where the details are supplied by configuration instead of an end-user.
+type AppRoleAuth struct {
+	name  string
+	mount string
+}
+
+// NewAppRoleAuth returns a new AppRoleAuth struct with the name and mount already configured.
+func NewAppRoleAuth() Auth {
+	return NewAppRoleAuthWithMount("approle")
+}
+
+// NewAppRoleAuthWithMount returns a new AppRoleAuth struct configured to use the given mount.
+func NewAppRoleAuthWithMount(mount string) Auth {
+	return &AppRoleAuth{
+		name:  "AppRole",
+		mount: mount,
+	}
+}
+
+// Name returns the name of the authentication type. This is used when building a list of supported authentication
+// types and should be a user friendly name.
+func (a *AppRoleAuth) Name() string {
+	return a.name
+}
+
+// Description returns the description of AppRole shown when choosing an authentication method.
+func (a *AppRoleAuth) Description() string {
+	return "Role ID and secret ID for machines and automation"
+}
+
+// Priority returns the priority of AppRole, which is low since its credentials are rarely entered by hand.
+func (a *AppRoleAuth) Priority() int {
+	return 20
+}
+
+// AuthDetails returns a map of detail names to their respective auth.Detail struct. The AppRole backend requires the
+// role ID and secret ID for logging in.
+func (a *AppRoleAuth) AuthDetails() map[string]*Detail {
+	return map[string]*Detail{
+		"role_id": {
+			Prompt: "Role ID: ",
+			Hidden: false,
+		},
+		"secret_id": {
+			Prompt: "Secret ID: ",
+			Hidden: true,
+		},
+	}
+}
+
+// GetPath returns the Vault path to write to for performing this type of authentication (i.e. auth/approle/login).
+func (a *AppRoleAuth) GetPath(details map[string]*Detail) string {
+	return fmt.Sprintf("auth/%s/login", a.mount)
+}
+
+// GetData returns a map of JSON data that will be written to the path returned by GetPath.
+func (a *AppRoleAuth) GetData(details map[string]*Detail) map[string]interface{} {
+	return map[string]interface{}{
+		"role_id":   details["role_id"].Value,
+		"secret_id": details["secret_id"].Value,
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewAppRoleAuth(t *testing.T) {
+	testAppRole := &AppRoleAuth{
+		name:  "AppRole",
+		mount: "approle",
+	}
+
+	result := NewAppRoleAuth()
+	assert.Equal(t, testAppRole, result)
+}
+
+func TestAppRoleAuth_GetPath(t *testing.T) {
+	testAppRole := NewAppRoleAuthWithMount("ci")
+	result := testAppRole.GetPath(testAppRole.AuthDetails())
+	assert.Equal(t, "auth/ci/login", result)
+}
+
+func TestAppRoleAuth_GetData(t *testing.T) {
+	testAppRole := NewAppRoleAuth()
+	details := testAppRole.AuthDetails()
+	details["role_id"].Value = "role"
+	details["secret_id"].Value = "secret"
+
+	result := testAppRole.GetData(details)
+	assert.Equal(t, "role", result["role_id"])
+	assert.Equal(t, "secret", result["secret_id"])
+}
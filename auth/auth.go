@@ -3,16 +3,41 @@
 // adding additional forms of authentication not currently supported by the package.
 package auth
 
+import (
+	"github.com/hashicorp/vault/api"
+	"sort"
+)
+
 //go:generate moq -out ../internal/mocks/authinterface.go -pkg mocks . Auth
 // Auth represents a form of authenticating with a Vault instance. See UserPassAuth for an example of how to properly
-// implement this interface.
+// implement this interface. The description and priority are used when presenting the authentication type to the
+// end-user, with higher priority types being listed first.
 type Auth interface {
 	Name() string
+	Description() string
+	Priority() int
 	GetData(map[string]*Detail) map[string]interface{}
 	GetPath(map[string]*Detail) string
 	AuthDetails() map[string]*Detail
 }
 
+//go:generate moq -out ../internal/mocks/interactiveauthinterface.go -pkg mocks . InteractiveAuth
+// InteractiveAuth represents a form of authentication which cannot be completed with a single write of the collected
+// details and instead requires a multi-step exchange with the Vault instance (i.e. a browser based OIDC login). The
+// client Login() function will call Authenticate in place of writing GetData to GetPath for any Auth which also
+// implements this interface.
+type InteractiveAuth interface {
+	Auth
+	Authenticate(Logical, map[string]*Detail) (*api.Secret, error)
+}
+
+// Logical represents the subset of the Vault logical API made available to an InteractiveAuth when performing its
+// exchange with the Vault instance.
+type Logical interface {
+	Write(path string, data map[string]interface{}) (*api.Secret, error)
+	ReadWithData(path string, data map[string][]string) (*api.Secret, error)
+}
+
 // Detail represents a piece of information given by the end-user and required for performing authentication.
 type Detail struct {
 	Value interface{}
@@ -24,17 +49,46 @@ type Detail struct {
 var Types = map[string]func() Auth{
 	NewUserPassAuth().Name(): NewUserPassAuth,
 	NewUserPassRadiusAuth().Name(): NewUserPassRadiusAuth,
+	NewLDAPAuth().Name(): NewLDAPAuth,
+	NewOIDCAuth().Name(): NewOIDCAuth,
+	NewAppRoleAuth().Name(): NewAppRoleAuth,
+	NewKubernetesAuth().Name(): NewKubernetesAuth,
+	NewCertAuth().Name(): NewCertAuth,
+	NewGitHubAuth().Name(): NewGitHubAuth,
+}
+
+// MountTypes is a map of every Vault auth backend type to a factory function which returns the associated
+// authentication type configured to use the given mount.
+var MountTypes = map[string]func(mount string) Auth{
+	"userpass": NewUserPassAuthWithMount,
+	"radius": NewUserPassRadiusAuthWithMount,
+	"ldap": NewLDAPAuthWithMount,
+	"oidc": NewOIDCAuthWithMount,
+	"approle": NewAppRoleAuthWithMount,
+	"kubernetes": func(mount string) Auth { return NewKubernetesAuthWithConfig(mount, "") },
+	"cert": func(mount string) Auth { return NewCertAuthWithConfig(mount, "") },
+	"github": NewGitHubAuthWithMount,
 }
 
-// GetAuthNames returns the name of every type of authentication currently supported by the auth package.
+// GetAuthNames returns the name of every type of authentication currently supported by the auth package. The names
+// are ordered by priority, highest first, and then alphabetically so that the order is stable between runs.
 func GetAuthNames() []string {
 	names := make([]string, len(Types))
+	priorities := make(map[string]int, len(Types))
 
 	i := 0
-	for name := range Types {
+	for name, factory := range Types {
 		names[i] = name
+		priorities[name] = factory().Priority()
 		i++
 	}
 
+	sort.Slice(names, func(i, j int) bool {
+		if priorities[names[i]] != priorities[names[j]] {
+			return priorities[names[i]] > priorities[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
 	return names
 }
\ No newline at end of file
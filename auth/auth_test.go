@@ -11,3 +11,18 @@ func TestGetAuthNames(t *testing.T) {
 
 	assert.Equal(t, expectedLength, gotLength)
 }
+
+func TestGetAuthNamesOrder(t *testing.T) {
+	names := GetAuthNames()
+	for i := 1; i < len(names); i++ {
+		previous := Types[names[i-1]]()
+		current := Types[names[i]]()
+		assert.GreaterOrEqual(t, previous.Priority(), current.Priority())
+		if previous.Priority() == current.Priority() {
+			assert.Less(t, names[i-1], names[i])
+		}
+	}
+
+	// The order should be identical between calls
+	assert.Equal(t, names, GetAuthNames())
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// CertAuth represents a form of authentication that uses the TLS client certificate presented when connecting to the
+// Vault instance. No details are required from the end-user since the certificate itself is the credential.
+type CertAuth struct {
+	name  string
+	mount string
+	role  string
+}
+
+// NewCertAuth returns a new CertAuth struct with the name and mount already configured.
+func NewCertAuth() Auth {
+	return NewCertAuthWithConfig("cert", "")
+}
+
+// NewCertAuthWithConfig returns a new CertAuth struct configured to use the given mount. If role is not empty, the
+// login is restricted to the certificate role with that name rather than trying all roles on the mount.
+func NewCertAuthWithConfig(mount string, role string) Auth {
+	return &CertAuth{
+		name:  "Cert",
+		mount: mount,
+		role:  role,
+	}
+}
+
+// Name returns the name of the authentication type. This is used when building a list of supported authentication
+// types and should be a user friendly name.
+func (c *CertAuth) Name() string {
+	return c.name
+}
+
+// Description returns the description of Cert shown when choosing an authentication method.
+func (c *CertAuth) Description() string {
+	return "TLS client certificate configured for the connection"
+}
+
+// Priority returns the priority of Cert, which is low since it needs a client certificate to be configured.
+func (c *CertAuth) Priority() int {
+	return 30
+}
+
+// AuthDetails returns an empty map as the cert backend does not require any details from the end-user.
+func (c *CertAuth) AuthDetails() map[string]*Detail {
+	return map[string]*Detail{}
+}
+
+// GetPath returns the Vault path to write to for performing this type of authentication (i.e. auth/cert/login).
+func (c *CertAuth) GetPath(details map[string]*Detail) string {
+	return fmt.Sprintf("auth/%s/login", c.mount)
+}
+
+// GetData returns a map of JSON data that will be written to the path returned by GetPath.
+func (c *CertAuth) GetData(details map[string]*Detail) map[string]interface{} {
+	data := map[string]interface{}{}
+	if c.role != "" {
+		data["name"] = c.role
+	}
+
+	return data
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewCertAuth(t *testing.T) {
+	testCert := &CertAuth{
+		name:  "Cert",
+		mount: "cert",
+	}
+
+	result := NewCertAuth()
+	assert.Equal(t, testCert, result)
+	assert.Empty(t, result.AuthDetails())
+}
+
+func TestCertAuth_GetPath(t *testing.T) {
+	testCert := NewCertAuthWithConfig("mtls", "")
+	assert.Equal(t, "auth/mtls/login", testCert.GetPath(testCert.AuthDetails()))
+}
+
+func TestCertAuth_GetData(t *testing.T) {
+	t.Run("With role", func(t *testing.T) {
+		testCert := NewCertAuthWithConfig("cert", "web")
+		assert.Equal(t, "web", testCert.GetData(testCert.AuthDetails())["name"])
+	})
+	t.Run("Without role", func(t *testing.T) {
+		testCert := NewCertAuth()
+		assert.Empty(t, testCert.GetData(testCert.AuthDetails()))
+	})
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// GitHubAuth represents a form of authentication that takes a GitHub personal access token.
+type GitHubAuth struct {
+	name  string
+	mount string
+}
+
+// NewGitHubAuth returns a new GitHubAuth struct with the name and mount already configured.
+func NewGitHubAuth() Auth {
+	return NewGitHubAuthWithMount("github")
+}
+
+// NewGitHubAuthWithMount returns a new GitHubAuth struct configured to use the given mount.
+func NewGitHubAuthWithMount(mount string) Auth {
+	return &GitHubAuth{
+		name:  "GitHub",
+		mount: mount,
+	}
+}
+
+// Name returns the name of the authentication type. This is used when building a list of supported authentication
+// types and should be a user friendly name.
+func (g *GitHubAuth) Name() string {
+	return g.name
+}
+
+// Description returns the description of GitHub shown when choosing an authentication method.
+func (g *GitHubAuth) Description() string {
+	return "GitHub personal access token"
+}
+
+// Priority returns the priority of GitHub when ordering authentication methods.
+func (g *GitHubAuth) Priority() int {
+	return 40
+}
+
+// AuthDetails returns a map of detail names to their respective auth.Detail struct. The GitHub backend only requires
+// a personal access token for logging in.
+func (g *GitHubAuth) AuthDetails() map[string]*Detail {
+	return map[string]*Detail{
+		"token": {
+			Prompt: "GitHub Token: ",
+			Hidden: true,
+		},
+	}
+}
+
+// GetPath returns the Vault path to write to for performing this type of authentication (i.e. auth/github/login).
+func (g *GitHubAuth) GetPath(details map[string]*Detail) string {
+	return fmt.Sprintf("auth/%s/login", g.mount)
+}
+
+// GetData returns a map of JSON data that will be written to the path returned by GetPath.
+func (g *GitHubAuth) GetData(details map[string]*Detail) map[string]interface{} {
+	return map[string]interface{}{
+		"token": details["token"].Value,
+	}
+}
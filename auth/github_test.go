@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewGitHubAuth(t *testing.T) {
+	testGitHub := &GitHubAuth{
+		name:  "GitHub",
+		mount: "github",
+	}
+
+	result := NewGitHubAuth()
+	assert.Equal(t, testGitHub, result)
+	assert.True(t, result.AuthDetails()["token"].Hidden)
+}
+
+func TestGitHubAuth_GetPath(t *testing.T) {
+	testGitHub := NewGitHubAuthWithMount("gh")
+	assert.Equal(t, "auth/gh/login", testGitHub.GetPath(testGitHub.AuthDetails()))
+}
+
+func TestGitHubAuth_GetData(t *testing.T) {
+	testGitHub := NewGitHubAuth()
+	details := testGitHub.AuthDetails()
+	details["token"].Value = "token"
+
+	result := testGitHub.GetData(details)
+	assert.Equal(t, "token", result["token"])
+}
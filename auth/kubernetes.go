@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"io/ioutil"
+	"strings"
+)
+
+// DefaultKubernetesTokenPath is the path Kubernetes projects the pod's service account token to by default.
+const DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// KubernetesAuth represents a form of authentication that uses the service account token of the pod vssh is running in.
+// The token is read from disk at login time, so the only detail required is the role to login with.
+type KubernetesAuth struct {
+	name      string
+	mount     string
+	tokenPath string
+}
+
+// NewKubernetesAuth returns a new KubernetesAuth struct with the name, mount, and token path already configured.
+func NewKubernetesAuth() Auth {
+	return NewKubernetesAuthWithConfig("kubernetes", DefaultKubernetesTokenPath)
+}
+
+// NewKubernetesAuthWithConfig returns a new KubernetesAuth struct configured to use the given mount and to read the
+// service account token from the given path. An empty token path falls back to DefaultKubernetesTokenPath.
+func NewKubernetesAuthWithConfig(mount string, tokenPath string) Auth {
+	if tokenPath == "" {
+		tokenPath = DefaultKubernetesTokenPath
+	}
+
+	return &KubernetesAuth{
+		name:      "Kubernetes",
+		mount:     mount,
+		tokenPath: tokenPath,
+	}
+}
+
+// Name returns the name of the authentication type. This is used when building a list of supported authentication
+// types and should be a user friendly name.
+func (k *KubernetesAuth) Name() string {
+	return k.name
+}
+
+// Description returns the description of Kubernetes shown when choosing an authentication method.
+func (k *KubernetesAuth) Description() string {
+	return "Service account token of the current Kubernetes pod"
+}
+
+// Priority returns the priority of Kubernetes, which is the lowest since it only works inside a pod.
+func (k *KubernetesAuth) Priority() int {
+	return 10
+}
+
+// AuthDetails returns a map of detail names to their respective auth.Detail struct. The Kubernetes backend only needs
+// the role to login with since the service account token is read from disk.
+func (k *KubernetesAuth) AuthDetails() map[string]*Detail {
+	return map[string]*Detail{
+		"role": {
+			Prompt: "Role: ",
+			Hidden: false,
+		},
+	}
+}
+
+// GetPath returns the Vault path to write to for performing this type of authentication (i.e. auth/kubernetes/login).
+func (k *KubernetesAuth) GetPath(details map[string]*Detail) string {
+	return fmt.Sprintf("auth/%s/login", k.mount)
+}
+
+// GetData returns a map of JSON data that will be written to the path returned by GetPath. The service account token
+// is added separately by Authenticate.
+func (k *KubernetesAuth) GetData(details map[string]*Detail) map[string]interface{} {
+	return map[string]interface{}{
+		"role": details["role"].Value,
+	}
+}
+
+// Authenticate reads the service account token and writes it along with the role to the path returned by GetPath.
+func (k *KubernetesAuth) Authenticate(l Logical, details map[string]*Detail) (*api.Secret, error) {
+	jwt, err := ioutil.ReadFile(k.tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account token: %w", err)
+	}
+
+	data := k.GetData(details)
+	data["jwt"] = strings.TrimSpace(string(jwt))
+
+	return l.Write(k.GetPath(details), data)
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewKubernetesAuth(t *testing.T) {
+	testKubernetes := &KubernetesAuth{
+		name:      "Kubernetes",
+		mount:     "kubernetes",
+		tokenPath: DefaultKubernetesTokenPath,
+	}
+
+	result := NewKubernetesAuth()
+	assert.Equal(t, testKubernetes, result)
+	assert.Equal(t, testKubernetes, NewKubernetesAuthWithConfig("kubernetes", ""))
+}
+
+func TestKubernetesAuth_GetPath(t *testing.T) {
+	testKubernetes := NewKubernetesAuthWithConfig("k8s", "")
+	assert.Equal(t, "auth/k8s/login", testKubernetes.GetPath(testKubernetes.AuthDetails()))
+}
+
+func TestKubernetesAuth_Authenticate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("With token file", func(t *testing.T) {
+		tokenPath := filepath.Join(dir, "token")
+		if err := ioutil.WriteFile(tokenPath, []byte("jwt\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		logical := &fakeLogical{}
+		testKubernetes := NewKubernetesAuthWithConfig("kubernetes", tokenPath).(InteractiveAuth)
+		details := testKubernetes.AuthDetails()
+		details["role"].Value = "jump"
+
+		_, err := testKubernetes.Authenticate(logical, details)
+		assert.Nil(t, err)
+		assert.Equal(t, "jwt", logical.writeData["jwt"])
+		assert.Equal(t, "jump", logical.writeData["role"])
+	})
+	t.Run("Without token file", func(t *testing.T) {
+		logical := &fakeLogical{}
+		testKubernetes := NewKubernetesAuthWithConfig("kubernetes", filepath.Join(dir, "missing")).(InteractiveAuth)
+		details := testKubernetes.AuthDetails()
+		details["role"].Value = "jump"
+
+		_, err := testKubernetes.Authenticate(logical, details)
+		assert.NotNil(t, err)
+		assert.Nil(t, logical.writeData)
+	})
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// LDAPAuth represents a form of authentication that takes a username and password and validates them against an LDAP
+// directory configured in Vault.
+type LDAPAuth struct {
+	name  string
+	mount string
+}
+
+// NewLDAPAuth returns a new LDAPAuth struct with the name and mount already configured.
+func NewLDAPAuth() Auth {
+	return NewLDAPAuthWithMount("ldap")
+}
+
+// NewLDAPAuthWithMount returns a new LDAPAuth struct configured to use the given mount. This is useful for Vault
+// instances which mount the LDAP backend at a non-default path (i.e. auth/corp-ldap).
+func NewLDAPAuthWithMount(mount string) Auth {
+	return &LDAPAuth{
+		name:  "LDAP",
+		mount: mount,
+	}
+}
+
+// Name returns the name of the authentication type. This is used when building a list of supported authentication
+// types and should be a user friendly name.
+func (l *LDAPAuth) Name() string {
+	return l.name
+}
+
+// Description returns the description of LDAP shown when choosing an authentication method.
+func (l *LDAPAuth) Description() string {
+	return "Username and password from the corporate LDAP directory"
+}
+
+// Priority returns the priority of LDAP when ordering authentication methods.
+func (l *LDAPAuth) Priority() int {
+	return 80
+}
+
+// AuthDetails returns a map of detail names to their respective auth.Detail struct. The LDAP backend requires the
+// directory username and password for logging in.
+func (l *LDAPAuth) AuthDetails() map[string]*Detail {
+	return map[string]*Detail{
+		"username": {
+			Prompt: "Username: ",
+			Hidden: false,
+		},
+		"password": {
+			Prompt: "Password: ",
+			Hidden: true,
+		},
+	}
+}
+
+// GetPath returns the Vault path to write to for performing this type of authentication
+// (i.e. auth/ldap/login/user).
+func (l *LDAPAuth) GetPath(details map[string]*Detail) string {
+	return fmt.Sprintf("auth/%s/login/%s", l.mount, details["username"].Value)
+}
+
+// GetData returns a map of JSON data that will be written to the path returned by GetPath.
+func (l *LDAPAuth) GetData(details map[string]*Detail) map[string]interface{} {
+	return map[string]interface{}{
+		"password": details["password"].Value,
+	}
+}
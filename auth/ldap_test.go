@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewLDAPAuth(t *testing.T) {
+	testLDAP := &LDAPAuth{
+		name:  "LDAP",
+		mount: "ldap",
+	}
+
+	result := NewLDAPAuth()
+	assert.Equal(t, testLDAP, result)
+}
+
+func TestNewLDAPAuthWithMount(t *testing.T) {
+	testLDAP := &LDAPAuth{
+		name:  "LDAP",
+		mount: "corp-ldap",
+	}
+
+	result := NewLDAPAuthWithMount("corp-ldap")
+	assert.Equal(t, testLDAP, result)
+}
+
+func TestLDAPAuth_GetPath(t *testing.T) {
+	username := "username"
+	mount := "ldap"
+	expected := fmt.Sprintf("auth/%s/login/%s", mount, username)
+	testLDAP := NewLDAPAuth()
+	details := testLDAP.AuthDetails()
+	details["username"].Value = username
+
+	result := testLDAP.GetPath(details)
+	assert.Equal(t, expected, result)
+}
+
+func TestLDAPAuth_GetData(t *testing.T) {
+	password := "password"
+	testLDAP := NewLDAPAuth()
+	details := testLDAP.AuthDetails()
+	details["password"].Value = password
+
+	result := testLDAP.GetData(details)
+	assert.Equal(t, password, result["password"])
+}
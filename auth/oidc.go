@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// defaultOIDCListenAddress is the address the local callback server listens on. Port 8250 matches the default used by
+// the Vault CLI and is therefore the port most commonly whitelisted in a role's allowed_redirect_uris.
+const defaultOIDCListenAddress = "localhost:8250"
+
+// defaultOIDCTimeout is how long to wait for the end-user to complete the login in their browser.
+const defaultOIDCTimeout = 2 * time.Minute
+
+// oidcCallback represents the query parameters passed back to the local callback server by the OIDC provider.
+type oidcCallback struct {
+	state string
+	code  string
+	err   error
+}
+
+// OIDCAuth represents a browser based form of authentication against the Vault OIDC backend. Since the login requires
+// a round trip through the OIDC provider, it implements InteractiveAuth rather than relying on a single write.
+type OIDCAuth struct {
+	name          string
+	mount         string
+	listenAddress string
+	timeout       time.Duration
+	openBrowser   func(url string) error
+	output        io.Writer
+}
+
+// NewOIDCAuth returns a new OIDCAuth struct with the name and mount already configured.
+func NewOIDCAuth() Auth {
+	return NewOIDCAuthWithMount("oidc")
+}
+
+// NewOIDCAuthWithMount returns a new OIDCAuth struct configured to use the given mount.
+func NewOIDCAuthWithMount(mount string) Auth {
+	return &OIDCAuth{
+		name:          "OIDC",
+		mount:         mount,
+		listenAddress: defaultOIDCListenAddress,
+		timeout:       defaultOIDCTimeout,
+		openBrowser:   openBrowser,
+		output:        os.Stdout,
+	}
+}
+
+// Name returns the name of the authentication type. This is used when building a list of supported authentication
+// types and should be a user friendly name.
+func (o *OIDCAuth) Name() string {
+	return o.name
+}
+
+// Description returns the description of OIDC shown when choosing an authentication method.
+func (o *OIDCAuth) Description() string {
+	return "Single sign-on through your identity provider in the browser"
+}
+
+// Priority returns the priority of OIDC when ordering authentication methods.
+func (o *OIDCAuth) Priority() int {
+	return 90
+}
+
+// AuthDetails returns a map of detail names to their respective auth.Detail struct. The OIDC backend only needs the
+// role to login with, which may be left blank to use the default role configured on the mount.
+func (o *OIDCAuth) AuthDetails() map[string]*Detail {
+	return map[string]*Detail{
+		"role": {
+			Prompt: "Role (leave blank for default): ",
+			Hidden: false,
+		},
+	}
+}
+
+// GetPath returns the Vault path to write to for starting this type of authentication (i.e. auth/oidc/oidc/auth_url).
+func (o *OIDCAuth) GetPath(details map[string]*Detail) string {
+	return fmt.Sprintf("auth/%s/oidc/auth_url", o.mount)
+}
+
+// GetData returns a map of JSON data that will be written to the path returned by GetPath in order to obtain the
+// provider's authorization URL.
+func (o *OIDCAuth) GetData(details map[string]*Detail) map[string]interface{} {
+	data := map[string]interface{}{
+		"redirect_uri": o.redirectURI(o.listenAddress),
+	}
+
+	if role, ok := details["role"]; ok && role.Value != nil {
+		data["role"] = role.Value
+	}
+
+	return data
+}
+
+// Authenticate performs the OIDC login flow. It starts a local callback server, requests the authorization URL from
+// Vault, sends the end-user to it and then exchanges the code returned to the callback server for a Vault token.
+func (o *OIDCAuth) Authenticate(l Logical, details map[string]*Detail) (*api.Secret, error) {
+	listener, err := net.Listen("tcp", o.listenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error starting callback listener: %w", err)
+	}
+	defer listener.Close()
+
+	nonce, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	// The listener may have been assigned a random port so the redirect is built from its actual address
+	data := o.GetData(details)
+	data["redirect_uri"] = o.redirectURI(listener.Addr().String())
+	data["client_nonce"] = nonce
+
+	secret, err := l.Write(o.GetPath(details), data)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no authorization url was returned from the server")
+	}
+
+	authURL, ok := secret.Data["auth_url"].(string)
+	if !ok || authURL == "" {
+		return nil, fmt.Errorf("no authorization url was returned from the server - check the role's allowed redirect uris include %s", data["redirect_uri"])
+	}
+
+	callbacks := make(chan oidcCallback, 1)
+	server := &http.Server{Handler: o.callbackHandler(callbacks)}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	fmt.Fprintf(o.output, "Complete the login via your OIDC provider. Launching browser to:\n\n    %s\n\n", authURL)
+	if err := o.openBrowser(authURL); err != nil {
+		fmt.Fprintln(o.output, "Unable to launch browser, please open the above URL manually")
+	}
+
+	var callback oidcCallback
+	select {
+	case callback = <-callbacks:
+	case <-time.After(o.timeout):
+		return nil, fmt.Errorf("timed out waiting for response from the OIDC provider")
+	}
+
+	if callback.err != nil {
+		return nil, callback.err
+	}
+
+	return l.ReadWithData(fmt.Sprintf("auth/%s/oidc/callback", o.mount), map[string][]string{
+		"state":        {callback.state},
+		"code":         {callback.code},
+		"client_nonce": {nonce},
+	})
+}
+
+// callbackHandler returns a http.Handler which captures the code and state returned by the OIDC provider and sends
+// them to the given channel.
+func (o *OIDCAuth) callbackHandler(callbacks chan<- oidcCallback) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oidc/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		callback := oidcCallback{
+			state: query.Get("state"),
+			code:  query.Get("code"),
+		}
+
+		if errMsg := query.Get("error_description"); errMsg != "" {
+			callback.err = fmt.Errorf("error from OIDC provider: %s", errMsg)
+		} else if callback.code == "" {
+			callback.err = fmt.Errorf("no code was returned from the OIDC provider")
+		}
+
+		if callback.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, "Login failed, you may close this window and return to vssh.")
+		} else {
+			fmt.Fprintln(w, "Login successful, you may close this window and return to vssh.")
+		}
+
+		// Only the first callback is of interest, any further requests are dropped
+		select {
+		case callbacks <- callback:
+		default:
+		}
+	})
+
+	return mux
+}
+
+// redirectURI returns the callback URL the OIDC provider should redirect to for the given listen address.
+func (o *OIDCAuth) redirectURI(address string) string {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Sprintf("http://%s/oidc/callback", address)
+	}
+	return fmt.Sprintf("http://localhost:%s/oidc/callback", port)
+}
+
+// newNonce returns a random value used to bind the authorization request to the callback exchange.
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// openBrowser attempts to open the given URL with the default browser of the current platform.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	return cmd.Start()
+}
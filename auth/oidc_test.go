@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeLogical is a small stand-in for the Vault logical API which emulates the OIDC backend endpoints and otherwise
+// returns a token for any write.
+type fakeLogical struct {
+	writeData map[string]interface{}
+	readPath  string
+	readData  map[string][]string
+}
+
+func (f *fakeLogical) Write(path string, data map[string]interface{}) (*api.Secret, error) {
+	f.writeData = data
+	redirectURI, ok := data["redirect_uri"].(string)
+	if !ok {
+		return &api.Secret{Auth: &api.SecretAuth{ClientToken: "token"}}, nil
+	}
+
+	return &api.Secret{
+		Data: map[string]interface{}{
+			"auth_url": fmt.Sprintf("https://provider.example.com/auth?redirect_uri=%s&state=state",
+				url.QueryEscape(redirectURI)),
+		},
+	}, nil
+}
+
+func (f *fakeLogical) ReadWithData(path string, data map[string][]string) (*api.Secret, error) {
+	f.readPath = path
+	f.readData = data
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: "token"}}, nil
+}
+
+// newTestOIDCAuth returns an OIDCAuth listening on a random port whose browser emulates the OIDC provider by
+// redirecting to the callback server with the given query.
+func newTestOIDCAuth(query string) *OIDCAuth {
+	o := NewOIDCAuth().(*OIDCAuth)
+	o.listenAddress = "127.0.0.1:0"
+	o.timeout = 5 * time.Second
+	o.output = ioutil.Discard
+	o.openBrowser = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		go http.Get(u.Query().Get("redirect_uri") + "?" + query)
+		return nil
+	}
+	return o
+}
+
+func TestNewOIDCAuth(t *testing.T) {
+	result := NewOIDCAuth().(*OIDCAuth)
+	assert.Equal(t, "OIDC", result.Name())
+	assert.Equal(t, "oidc", result.mount)
+	assert.Equal(t, defaultOIDCListenAddress, result.listenAddress)
+}
+
+func TestOIDCAuth_GetPath(t *testing.T) {
+	testOIDC := NewOIDCAuthWithMount("sso")
+	assert.Equal(t, "auth/sso/oidc/auth_url", testOIDC.GetPath(testOIDC.AuthDetails()))
+}
+
+func TestOIDCAuth_GetData(t *testing.T) {
+	testOIDC := NewOIDCAuth()
+	details := testOIDC.AuthDetails()
+	details["role"].Value = "engineer"
+
+	result := testOIDC.GetData(details)
+	assert.Equal(t, "engineer", result["role"])
+	assert.Equal(t, "http://localhost:8250/oidc/callback", result["redirect_uri"])
+}
+
+func TestOIDCAuth_Authenticate(t *testing.T) {
+	t.Run("With successful callback", func(t *testing.T) {
+		logical := &fakeLogical{}
+		testOIDC := newTestOIDCAuth("state=state&code=code")
+
+		secret, err := testOIDC.Authenticate(logical, testOIDC.AuthDetails())
+		assert.Nil(t, err)
+		assert.Equal(t, "token", secret.Auth.ClientToken)
+		assert.Equal(t, "auth/oidc/oidc/callback", logical.readPath)
+		assert.Equal(t, []string{"code"}, logical.readData["code"])
+		assert.Equal(t, []string{"state"}, logical.readData["state"])
+		assert.Equal(t, []string{logical.writeData["client_nonce"].(string)}, logical.readData["client_nonce"])
+	})
+	t.Run("With provider error", func(t *testing.T) {
+		logical := &fakeLogical{}
+		testOIDC := newTestOIDCAuth("error=access_denied&error_description=denied")
+
+		_, err := testOIDC.Authenticate(logical, testOIDC.AuthDetails())
+		assert.NotNil(t, err)
+		assert.Empty(t, logical.readPath)
+	})
+}
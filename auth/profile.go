@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// Profile represents a named authentication method configured by the end-user. It pairs a Vault auth backend type with
+// the mount it lives at and any default values for the details it requires (i.e. a username).
+type Profile struct {
+	Name        string            `mapstructure:"name"`
+	Description string            `mapstructure:"description"`
+	Type        string            `mapstructure:"type"`
+	Mount       string            `mapstructure:"mount"`
+	Defaults    map[string]string `mapstructure:"defaults"`
+}
+
+// NewAuth returns a new Auth for the profile's type configured to use its mount. If no mount is set, the type is used
+// as the mount which matches the default mount used by Vault.
+func (p *Profile) NewAuth() (Auth, error) {
+	factory, ok := MountTypes[p.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown authentication type %q for %q", p.Type, p.Name)
+	}
+
+	mount := p.Mount
+	if mount == "" {
+		mount = p.Type
+	}
+
+	return factory(mount), nil
+}
+
+// SetDefaults sets the value of each of the given details which has a default configured in the profile.
+func (p *Profile) SetDefaults(details map[string]*Detail) {
+	for name, value := range p.Defaults {
+		if detail, ok := details[name]; ok {
+			detail.Value = value
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProfile_NewAuth(t *testing.T) {
+	t.Run("With mount", func(t *testing.T) {
+		profile := Profile{Name: "Corp", Type: "userpass", Mount: "corp-userpass"}
+		result, err := profile.NewAuth()
+		assert.Nil(t, err)
+		assert.Equal(t, NewUserPassAuthWithMount("corp-userpass"), result)
+	})
+	t.Run("Without mount", func(t *testing.T) {
+		profile := Profile{Name: "Directory", Type: "ldap"}
+		result, err := profile.NewAuth()
+		assert.Nil(t, err)
+		assert.Equal(t, NewLDAPAuth(), result)
+	})
+	t.Run("With unknown type", func(t *testing.T) {
+		profile := Profile{Name: "Unknown", Type: "unknown"}
+		_, err := profile.NewAuth()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestProfile_SetDefaults(t *testing.T) {
+	profile := Profile{
+		Type:     "userpass",
+		Defaults: map[string]string{"username": "test", "missing": "value"},
+	}
+	details := NewUserPassAuth().AuthDetails()
+
+	profile.SetDefaults(details)
+	assert.Equal(t, "test", details["username"].Value)
+	assert.Nil(t, details["password"].Value)
+	assert.NotContains(t, details, "missing")
+}
+
+func TestMountTypes(t *testing.T) {
+	for authType, factory := range MountTypes {
+		assert.NotNil(t, factory(authType), authType)
+	}
+}
@@ -6,23 +6,39 @@ import (
 
 // UserPassAuth represents a form of authentication that takes a username and password.
 type UserPassAuth struct {
-	name string
-	mount string
+	name        string
+	description string
+	priority    int
+	mount       string
 }
 
 // NewUserPassAuth returns a new UserPassAuth struct with the name and mount already configured.
 func NewUserPassAuth() Auth {
+	return NewUserPassAuthWithMount("userpass")
+}
+
+// NewUserPassAuthWithMount returns a new UserPassAuth struct configured to use the given mount.
+func NewUserPassAuthWithMount(mount string) Auth {
 	return &UserPassAuth{
-		name: "Userpass",
-		mount: "userpass",
+		name:        "Userpass",
+		description: "Username and password stored in Vault",
+		priority:    100,
+		mount:       mount,
 	}
 }
 
 // NewUserPassRadiusAuth returns a new UserPassAuth struct with the name and mount already configured for Radius.
 func NewUserPassRadiusAuth() Auth {
+	return NewUserPassRadiusAuthWithMount("radius")
+}
+
+// NewUserPassRadiusAuthWithMount returns a new UserPassAuth struct configured for Radius to use the given mount.
+func NewUserPassRadiusAuthWithMount(mount string) Auth {
 	return &UserPassAuth{
-		name: "Radius",
-		mount: "radius",
+		name:        "Radius",
+		description: "Username and password verified by a RADIUS server",
+		priority:    50,
+		mount:       mount,
 	}
 }
 
@@ -32,6 +48,16 @@ func (u *UserPassAuth) Name() string {
 	return u.name
 }
 
+// Description returns the description set by the constructor, which differs between Userpass and Radius.
+func (u *UserPassAuth) Description() string {
+	return u.description
+}
+
+// Priority returns the priority set by the constructor, which differs between Userpass and Radius.
+func (u *UserPassAuth) Priority() int {
+	return u.priority
+}
+
 // AuthDetails returns a map of detail names to their respective auth.Detail struct. This is used by the ui package to
 // automatically collect the necessary authentication details required for this authentication type from the end-user.
 // For example, the UserPassAuth type asks for the username and password for logging in.
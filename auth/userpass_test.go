@@ -8,8 +8,10 @@ import (
 
 func TestNewUserPassAuth(t *testing.T) {
 	testUP := &UserPassAuth{
-		name: "Userpass",
-		mount: "userpass",
+		name:        "Userpass",
+		description: "Username and password stored in Vault",
+		priority:    100,
+		mount:       "userpass",
 	}
 
 	result := NewUserPassAuth()
@@ -18,8 +20,10 @@ func TestNewUserPassAuth(t *testing.T) {
 
 func TestNewUserPassRadiusAuth(t *testing.T) {
 	testUP := &UserPassAuth{
-		name: "Radius",
-		mount: "radius",
+		name:        "Radius",
+		description: "Username and password verified by a RADIUS server",
+		priority:    50,
+		mount:       "radius",
 	}
 
 	result := NewUserPassRadiusAuth()
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// signCapability is the capability required on the signing path of the SSH backend in order to sign a public key.
+const signCapability = "update"
+
+// CapabilityError is returned by CheckSignCapability when the token is not permitted to sign with the given role.
+type CapabilityError struct {
+	Mount        string
+	Role         string
+	Path         string
+	Capability   string
+	Capabilities []string
+}
+
+// Error returns a description of the error which names the role, mount and missing capability.
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("the current token is not permitted to sign with role %q on mount %q: the %q capability is "+
+		"required on %s but the token's policies only grant [%s]", e.Role, e.Mount, e.Capability, e.Path,
+		strings.Join(e.Capabilities, ", "))
+}
+
+// CheckSignCapability uses sys/capabilities-self to check the token configured for the underlying API client is
+// permitted to sign SSH public keys with the given role and mount point. A *CapabilityError is returned if it is not.
+// The request is made in the SSH namespace if one has been set.
+func (c *VaultClient) CheckSignCapability(mount string, role string) error {
+	if mount == "" {
+		mount = "ssh"
+	}
+
+	path := fmt.Sprintf("%s/sign/%s", mount, role)
+	var capabilities []string
+	err := c.inNamespace(c.sshNamespace, func() error {
+		var err error
+		capabilities, err = c.api.Sys().CapabilitiesSelf(path)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, capability := range capabilities {
+		if capability == signCapability || capability == "root" {
+			return nil
+		}
+	}
+
+	return &CapabilityError{
+		Mount:        mount,
+		Role:         role,
+		Path:         path,
+		Capability:   signCapability,
+		Capabilities: capabilities,
+	}
+}
@@ -3,15 +3,46 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/jmgilman/vssh/auth"
+	"io/ioutil"
+	"strings"
+	"time"
 )
 
+// caPublicKeyTimeout is how long to wait for the CA public key, including retries, before giving up on it.
+const caPublicKeyTimeout = 5 * time.Second
+
 // VaultClient is a small wrapper around the Vault API client. It provides additional functionality needed by vssh such
 // as handling authentication a client and signing SSH public keys.
 type VaultClient struct {
-	api *api.Client
+	api           *api.Client
+	authNamespace string
+	sshNamespace  string
+	agent         bool
+}
+
+// TokenInfo represents the details of a token which are relevant to deciding whether it can still be used.
+type TokenInfo struct {
+	TTL         time.Duration
+	CreationTTL time.Duration
+	Renewable   bool
+	Policies    []string
+}
+
+// SignOptions represents the optional parameters which can be requested when signing a SSH public key. Empty fields
+// are left to the defaults of the role being signed with.
+type SignOptions struct {
+	ValidPrincipals []string          `mapstructure:"principals"`
+	TTL             time.Duration     `mapstructure:"cert_ttl"`
+	KeyID           string            `mapstructure:"key_id"`
+	Extensions      map[string]string `mapstructure:"extensions"`
+	CriticalOptions map[string]string `mapstructure:"critical_options"`
 }
 
 // NewClient returns a new VaultClient with the underlying API client configured with the given api.Config.
@@ -21,7 +52,8 @@ func NewClient(c *api.Config) (*VaultClient, error) {
 		return &VaultClient{}, err
 	}
 	return &VaultClient{
-		api: apiClient,
+		api:   apiClient,
+		agent: c.AgentAddress != "",
 	}, nil
 }
 
@@ -35,17 +67,56 @@ func NewDefaultClient() (*VaultClient, error) {
 	return NewClient(api.DefaultConfig())
 }
 
+// NewDefaultClientWithTLS returns a new VaultClient with the underlying API client configured with the Vault default
+// values and the given TLS configuration applied on top of them. Any empty fields in the TLS configuration leave the
+// default value (i.e. from $VAULT_CACERT) in place.
+func NewDefaultClientWithTLS(t *api.TLSConfig) (*VaultClient, error) {
+	return NewDefaultClientWithAgent(t, "")
+}
+
+// NewDefaultClientWithAgent returns a new VaultClient configured the same as NewDefaultClientWithTLS but which sends all
+// of its requests through the Vault Agent listening at the given address. The address may point to a Unix domain socket
+// (i.e. unix:///run/vault-agent.sock). An empty address leaves the default value (i.e. from $VAULT_AGENT_ADDR) in place.
+func NewDefaultClientWithAgent(t *api.TLSConfig, agentAddress string) (*VaultClient, error) {
+	config := api.DefaultConfig()
+	if err := config.ConfigureTLS(t); err != nil {
+		return &VaultClient{}, err
+	}
+
+	if agentAddress != "" {
+		config.AgentAddress = agentAddress
+	}
+
+	return NewClient(config)
+}
+
 // Login takes an authentication type along with its associated details and attempts to authenticate against the
 // configured Vault instance. If authentication is successful, the token returned from the Vault instance will be
-// automatically set to the underlying API client.
+// automatically set to the underlying API client. Authentication types which implement auth.InteractiveAuth are given
+// control of the exchange with the Vault instance instead of writing their data in a single request. If the login must
+// be validated with MFA, a *MFARequiredError is returned which should be answered with ValidateMFA.
 func (c *VaultClient) Login(a auth.Auth, d map[string]*auth.Detail) error {
-	secret, err := c.api.Logical().Write(a.GetPath(d), a.GetData(d))
+	var secret *api.Secret
+	logical := &loginLogical{api: c.api}
+	err := c.inNamespace(c.authNamespace, func() error {
+		var err error
+		if i, ok := a.(auth.InteractiveAuth); ok {
+			secret, err = i.Authenticate(logical, d)
+		} else {
+			secret, err = logical.Write(a.GetPath(d), a.GetData(d))
+		}
+		return err
+	})
 
 	if err != nil {
 		return err
 	}
 
-	if secret.Auth == nil {
+	if logical.mfaRequirement != nil {
+		return &MFARequiredError{Requirement: logical.mfaRequirement}
+	}
+
+	if secret == nil || secret.Auth == nil {
 		return fmt.Errorf("login returned an empty token")
 	}
 
@@ -54,8 +125,14 @@ func (c *VaultClient) Login(a auth.Auth, d map[string]*auth.Detail) error {
 }
 
 // SignPubKey will use the underlying API client to attempt to sign the given SSH public key with the given role and
-// mount point.
+// mount point. The request is made in the SSH namespace if one has been set.
 func (c *VaultClient) SignPubKey(mount string, role string, key []byte) (string, error) {
+	return c.SignPubKeyWithOptions(mount, role, key, &SignOptions{})
+}
+
+// SignPubKeyWithOptions performs the same signing as SignPubKey but also requests the given options. Note that the role
+// must permit the options requested, i.e. allow_user_key_ids must be set in order to request a key ID.
+func (c *VaultClient) SignPubKeyWithOptions(mount string, role string, key []byte, opts *SignOptions) (string, error) {
 	var ssh *api.SSH
 	// The SSH method sets the mount to its default value of "ssh"
 	if mount == "" {
@@ -69,8 +146,29 @@ func (c *VaultClient) SignPubKey(mount string, role string, key []byte) (string,
 		"cert_type": "user",
 	}
 
+	if len(opts.ValidPrincipals) > 0 {
+		data["valid_principals"] = strings.Join(opts.ValidPrincipals, ",")
+	}
+	if opts.TTL > 0 {
+		data["ttl"] = fmt.Sprintf("%ds", int64(opts.TTL.Seconds()))
+	}
+	if opts.KeyID != "" {
+		data["key_id"] = opts.KeyID
+	}
+	if len(opts.Extensions) > 0 {
+		data["extensions"] = opts.Extensions
+	}
+	if len(opts.CriticalOptions) > 0 {
+		data["critical_options"] = opts.CriticalOptions
+	}
+
 	// SignKey is a nice API wrapper which handles most of the logic for signing a key
-	result, err := ssh.SignKey(role, data)
+	var result *api.Secret
+	err := c.inNamespace(c.sshNamespace, func() error {
+		var err error
+		result, err = ssh.SignKey(role, data)
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
@@ -87,11 +185,47 @@ func (c *VaultClient) SignPubKey(mount string, role string, key []byte) (string,
 	return signedKey, nil
 }
 
+// GetCAPublicKey returns the public key of the CA which signs SSH public keys for the given mount point in the
+// authorized_keys format. The key is read from the unauthenticated public_key endpoint of the mount, in the SSH
+// namespace if one has been set. The request, including any retries, gives up after caPublicKeyTimeout.
+func (c *VaultClient) GetCAPublicKey(mount string) ([]byte, error) {
+	if mount == "" {
+		mount = "ssh"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), caPublicKeyTimeout)
+	defer cancel()
+
+	var key []byte
+	err := c.inNamespace(c.sshNamespace, func() error {
+		resp, err := c.api.RawRequestWithContext(ctx, c.api.NewRequest("GET", fmt.Sprintf("/v1/%s/public_key", mount)))
+		if resp != nil {
+			defer resp.Body.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		key, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(key)) == 0 {
+		return nil, fmt.Errorf("no CA public key was returned from the server")
+	}
+
+	return key, nil
+}
+
 // Authenticated performs a lookup of the underlying API client which by nature requires a valid token. If the lookup
 // fails it will return false, indicating the client does not have a valid token. If the lookup succeeds, it returns
 // true.
 func (c *VaultClient) Authenticated() bool {
-	_, err := c.api.Auth().Token().LookupSelf()
+	_, err := c.LookupToken()
 	if err != nil {
 		return false
 	} else {
@@ -99,6 +233,110 @@ func (c *VaultClient) Authenticated() bool {
 	}
 }
 
+// LookupToken returns the details of the token configured for the underlying API client. A TTL of zero indicates the
+// token never expires (i.e. a root token).
+func (c *VaultClient) LookupToken() (*TokenInfo, error) {
+	var secret *api.Secret
+	err := c.inNamespace(c.authNamespace, func() error {
+		var err error
+		secret, err = c.api.Auth().Token().LookupSelf()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokenInfo(secret)
+}
+
+// RenewToken renews the token configured for the underlying API client by the given increment and returns its updated
+// details. An increment of zero renews the token by its default TTL.
+func (c *VaultClient) RenewToken(increment time.Duration) (*TokenInfo, error) {
+	var secret *api.Secret
+	err := c.inNamespace(c.authNamespace, func() error {
+		var err error
+		secret, err = c.api.Auth().Token().RenewSelf(int(increment.Seconds()))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokenInfo(secret)
+}
+
+// AuthenticatedWithTTL performs the same check as Authenticated but also requires the token to remain valid for at
+// least minTTL. Renewable tokens with less than renewThreshold remaining are renewed first, so only tokens which can't
+// be renewed past minTTL are considered unauthenticated.
+func (c *VaultClient) AuthenticatedWithTTL(minTTL time.Duration, renewThreshold time.Duration) bool {
+	info, err := c.LookupToken()
+	if err != nil {
+		return false
+	}
+
+	// Tokens without a TTL never expire
+	if info.TTL == 0 {
+		return true
+	}
+
+	// Renewing by the TTL the token was created with restores it to its full lifetime
+	if info.Renewable && info.TTL < renewThreshold {
+		if renewed, err := c.RenewToken(info.CreationTTL); err == nil {
+			info = renewed
+		}
+	}
+
+	return info.TTL >= minTTL
+}
+
+// RevokeToken revokes the token configured for the underlying API client using auth/token/revoke-self and then clears
+// it from the client.
+func (c *VaultClient) RevokeToken() error {
+	err := c.inNamespace(c.authNamespace, func() error {
+		return c.api.Auth().Token().RevokeSelf("")
+	})
+	if err != nil {
+		return err
+	}
+
+	c.api.ClearToken()
+	return nil
+}
+
+// newTokenInfo returns the TokenInfo for the given token lookup or renewal response.
+func newTokenInfo(secret *api.Secret) (*TokenInfo, error) {
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return nil, err
+	}
+
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := secret.TokenPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only lookups include the creation TTL, renewals leave it as zero
+	var creationTTL int64
+	if value, ok := secret.Data["creation_ttl"].(json.Number); ok {
+		creationTTL, err = value.Int64()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &TokenInfo{
+		TTL:         ttl,
+		CreationTTL: time.Duration(creationTTL) * time.Second,
+		Renewable:   renewable,
+		Policies:    policies,
+	}, nil
+}
+
 // Available checks if the configured Vault instance is either sealed or not initialized, returning false if either of
 // those conditions are true.
 func (c *VaultClient) Available() (bool, error) {
@@ -114,9 +352,10 @@ func (c *VaultClient) Available() (bool, error) {
 	return false, nil
 }
 
-// SetConfigValues provides a method for setting the server and token of the underlying API client.
+// SetConfigValues provides a method for setting the server and token of the underlying API client. The server is
+// ignored when requests are sent through a Vault Agent since the agent forwards them to the server it is configured with.
 func (c *VaultClient) SetConfigValues(server string, token string) error {
-	if server != "" {
+	if server != "" && !c.agent {
 		if err := c.api.SetAddress(server); err != nil {
 			return err
 		}
@@ -129,6 +368,52 @@ func (c *VaultClient) SetConfigValues(server string, token string) error {
 	return nil
 }
 
+// SetNamespace sets the Vault Enterprise namespace used for all requests made by the underlying API client. An empty
+// namespace leaves the existing namespace (i.e. from $VAULT_NAMESPACE) in place.
+func (c *VaultClient) SetNamespace(namespace string) {
+	if namespace != "" {
+		c.api.SetNamespace(namespace)
+	}
+}
+
+// SetAuthNamespace sets the namespace used for logging in and for token operations, overriding the namespace of the
+// underlying API client. Tokens are only valid in the namespace they were issued in and its children, so this should
+// be the namespace the authentication mount lives in. An empty namespace removes the override.
+func (c *VaultClient) SetAuthNamespace(namespace string) {
+	c.authNamespace = namespace
+}
+
+// SetSSHNamespace sets the namespace used for signing SSH public keys, overriding the namespace of the underlying API
+// client. An empty namespace removes the override.
+func (c *VaultClient) SetSSHNamespace(namespace string) {
+	c.sshNamespace = namespace
+}
+
+// Namespace returns the namespace configured for the underlying API client.
+func (c *VaultClient) Namespace() string {
+	return c.api.Headers().Get(consts.NamespaceHeaderName)
+}
+
+// inNamespace calls the given function with the underlying API client temporarily configured to use the given
+// namespace. An empty namespace leaves the namespace of the underlying API client unchanged.
+func (c *VaultClient) inNamespace(namespace string, f func() error) error {
+	if namespace == "" {
+		return f()
+	}
+
+	headers := c.api.Headers()
+	defer c.api.SetHeaders(headers)
+	c.api.SetNamespace(namespace)
+
+	return f()
+}
+
+// UsingAgent returns true if requests are sent through a Vault Agent. The agent is expected to authenticate requests
+// with its auto-auth token, so logging in is unnecessary.
+func (c *VaultClient) UsingAgent() bool {
+	return c.agent
+}
+
 // Address returns the Vault instance address configured for the underlying API client.
 func (c *VaultClient) Address() string {
 	return c.api.Address()
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"io/ioutil"
+)
+
+// MFAMethod represents a single MFA method which can be used to satisfy a constraint of a MFARequirement.
+type MFAMethod struct {
+	Type         string `json:"type"`
+	ID           string `json:"id"`
+	UsesPasscode bool   `json:"uses_passcode"`
+}
+
+// MFAConstraint represents a set of MFA methods where any one of them must be validated.
+type MFAConstraint struct {
+	Any []MFAMethod `json:"any"`
+}
+
+// MFARequirement represents the challenge returned by a Vault instance when a login must be validated with MFA before
+// a token is issued.
+type MFARequirement struct {
+	RequestID   string                   `json:"mfa_request_id"`
+	Constraints map[string]MFAConstraint `json:"mfa_constraints"`
+}
+
+// MFARequiredError is returned by Login when the login succeeded but must be validated with MFA. The requirement it
+// contains should be answered with ValidateMFA in order to obtain a token.
+type MFARequiredError struct {
+	Requirement *MFARequirement
+}
+
+// Error returns a description of the error.
+func (e *MFARequiredError) Error() string {
+	return "login requires multi-factor authentication"
+}
+
+// ValidateMFA completes a login which returned a MFARequiredError using the given payload of MFA method IDs to their
+// passcodes. If validation is successful, the token returned from the Vault instance will be automatically set to the
+// underlying API client.
+func (c *VaultClient) ValidateMFA(requestID string, payload map[string][]string) error {
+	var secret *api.Secret
+	err := c.inNamespace(c.authNamespace, func() error {
+		var err error
+		secret, err = c.api.Logical().Write("sys/mfa/validate", map[string]interface{}{
+			"mfa_request_id": requestID,
+			"mfa_payload":    payload,
+		})
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return fmt.Errorf("mfa validation returned an empty token")
+	}
+
+	c.api.SetToken(secret.Auth.ClientToken)
+	return nil
+}
+
+// loginLogical implements auth.Logical for performing logins. The API client drops the MFA requirement when parsing a
+// login response, so reads and writes are performed as raw requests in order to capture it.
+type loginLogical struct {
+	api            *api.Client
+	mfaRequirement *MFARequirement
+}
+
+// Write writes the given data to the given path and records any MFA requirement found in the response.
+func (l *loginLogical) Write(path string, data map[string]interface{}) (*api.Secret, error) {
+	r := l.api.NewRequest("PUT", "/v1/"+path)
+	if err := r.SetJSONBody(data); err != nil {
+		return nil, err
+	}
+
+	return l.do(r)
+}
+
+// ReadWithData reads from the given path using the given query data and records any MFA requirement found in the
+// response (i.e. from an OIDC callback).
+func (l *loginLogical) ReadWithData(path string, data map[string][]string) (*api.Secret, error) {
+	r := l.api.NewRequest("GET", "/v1/"+path)
+	for key, values := range data {
+		r.Params[key] = values
+	}
+
+	return l.do(r)
+}
+
+// do performs the given request, records any MFA requirement found in the response and returns the parsed response.
+func (l *loginLogical) do(r *api.Request) (*api.Secret, error) {
+	resp, err := l.api.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var mfaResponse struct {
+		Auth *struct {
+			MFARequirement *MFARequirement `json:"mfa_requirement"`
+		} `json:"auth"`
+	}
+	if err := json.Unmarshal(body, &mfaResponse); err == nil && mfaResponse.Auth != nil {
+		l.mfaRequirement = mfaResponse.Auth.MFARequirement
+	}
+
+	return api.ParseSecret(bytes.NewReader(body))
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// HostDefaults represents the signing options configured for hosts matching a pattern (i.e. *.prod.example.com).
+type HostDefaults struct {
+	Host        string `mapstructure:"host"`
+	SignOptions `mapstructure:",squash"`
+}
+
+// MatchHostDefaults returns the first of the given host defaults whose pattern matches the given host, or nil if none
+// of them do. Patterns use the syntax of path.Match.
+func MatchHostDefaults(defaults []HostDefaults, host string) (*HostDefaults, error) {
+	for i := range defaults {
+		matched, err := path.Match(defaults[i].Host, host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host pattern %q: %w", defaults[i].Host, err)
+		}
+		if matched {
+			return &defaults[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ApplyDefaults replaces the options with the non-empty options of the given defaults. The principals, ttl and key ID
+// are kept when the given set of explicit options, keyed by their mapstructure names (i.e. cert_ttl), includes them.
+func (o *SignOptions) ApplyDefaults(defaults SignOptions, explicit map[string]bool) {
+	if len(defaults.ValidPrincipals) > 0 && !explicit["principals"] {
+		o.ValidPrincipals = defaults.ValidPrincipals
+	}
+	if defaults.TTL > 0 && !explicit["cert_ttl"] {
+		o.TTL = defaults.TTL
+	}
+	if defaults.KeyID != "" && !explicit["key_id"] {
+		o.KeyID = defaults.KeyID
+	}
+	if len(defaults.Extensions) > 0 {
+		o.Extensions = defaults.Extensions
+	}
+	if len(defaults.CriticalOptions) > 0 {
+		o.CriticalOptions = defaults.CriticalOptions
+	}
+}
+
+// MergeKeyValues returns a copy of the given map with the given pairs in the form of name=value added to it. A pair
+// without a value (i.e. permit-pty) is added with an empty value. The given kind names the pairs in errors.
+func MergeKeyValues(values map[string]string, pairs []string, kind string) (map[string]string, error) {
+	merged := make(map[string]string, len(values)+len(pairs))
+	for name, value := range values {
+		merged[name] = value
+	}
+
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("invalid %s %q - must be in the form of name=value", kind, pair)
+		}
+
+		if len(parts) == 2 {
+			merged[parts[0]] = parts[1]
+		} else {
+			merged[parts[0]] = ""
+		}
+	}
+
+	return merged, nil
+}
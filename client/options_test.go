@@ -0,0 +1,120 @@
+package client
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMatchHostDefaults(t *testing.T) {
+	defaults := []HostDefaults{
+		{Host: "*.prod.example.com", SignOptions: SignOptions{KeyID: "prod"}},
+		{Host: "web?", SignOptions: SignOptions{KeyID: "web"}},
+		{Host: "*", SignOptions: SignOptions{KeyID: "any"}},
+	}
+
+	tests := []struct {
+		host  string
+		keyID string
+	}{
+		{"db1.prod.example.com", "prod"},
+		{"web1", "web"},
+		{"web10", "any"},
+		{"prod.example.com", "any"},
+		{"", "any"},
+	}
+
+	for _, test := range tests {
+		result, err := MatchHostDefaults(defaults, test.host)
+		if assert.Nil(t, err, test.host) && assert.NotNil(t, result, test.host) {
+			assert.Equal(t, test.keyID, result.KeyID, test.host)
+		}
+	}
+
+	t.Run("Without match", func(t *testing.T) {
+		result, err := MatchHostDefaults(defaults[:2], "db1.example.com")
+		assert.Nil(t, err)
+		assert.Nil(t, result)
+	})
+	t.Run("With invalid pattern", func(t *testing.T) {
+		_, err := MatchHostDefaults([]HostDefaults{{Host: "web[1"}}, "web1")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSignOptions_ApplyDefaults(t *testing.T) {
+	configured := SignOptions{
+		ValidPrincipals: []string{"jdoe"},
+		TTL:             time.Hour,
+		KeyID:           "jdoe",
+		Extensions:      map[string]string{"permit-pty": ""},
+		CriticalOptions: map[string]string{"source-address": "10.0.0.0/8"},
+	}
+	defaults := SignOptions{
+		ValidPrincipals: []string{"deploy"},
+		TTL:             time.Minute,
+		KeyID:           "deploy",
+		Extensions:      map[string]string{"permit-port-forwarding": ""},
+		CriticalOptions: map[string]string{"force-command": "uptime"},
+	}
+
+	tests := []struct {
+		name     string
+		defaults SignOptions
+		explicit map[string]bool
+		expected SignOptions
+	}{
+		{"With defaults", defaults, nil, defaults},
+		{"With empty defaults", SignOptions{}, nil, configured},
+		{
+			"With explicit options",
+			defaults,
+			map[string]bool{"principals": true, "cert_ttl": true, "key_id": true},
+			SignOptions{
+				ValidPrincipals: configured.ValidPrincipals,
+				TTL:             configured.TTL,
+				KeyID:           configured.KeyID,
+				Extensions:      defaults.Extensions,
+				CriticalOptions: defaults.CriticalOptions,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		opts := configured
+		opts.ApplyDefaults(test.defaults, test.explicit)
+		assert.Equal(t, test.expected, opts, test.name)
+	}
+}
+
+func TestMergeKeyValues(t *testing.T) {
+	tests := []struct {
+		values   map[string]string
+		pairs    []string
+		expected map[string]string
+	}{
+		{nil, nil, map[string]string{}},
+		{map[string]string{"permit-pty": ""}, nil, map[string]string{"permit-pty": ""}},
+		{nil, []string{"permit-pty"}, map[string]string{"permit-pty": ""}},
+		{nil, []string{"source-address=10.0.0.0/8,192.168.0.0/16"}, map[string]string{"source-address": "10.0.0.0/8,192.168.0.0/16"}},
+		{nil, []string{"force-command=echo a=b"}, map[string]string{"force-command": "echo a=b"}},
+		{map[string]string{"permit-pty": "", "force-command": "uptime"}, []string{"force-command=w"}, map[string]string{"permit-pty": "", "force-command": "w"}},
+	}
+
+	for _, test := range tests {
+		result, err := MergeKeyValues(test.values, test.pairs, "extension")
+		assert.Nil(t, err, test.pairs)
+		assert.Equal(t, test.expected, result, test.pairs)
+	}
+
+	t.Run("Without name", func(t *testing.T) {
+		_, err := MergeKeyValues(nil, []string{"=value"}, "extension")
+		assert.NotNil(t, err)
+	})
+	t.Run("Leaves given map unchanged", func(t *testing.T) {
+		values := map[string]string{"permit-pty": ""}
+		_, err := MergeKeyValues(values, []string{"permit-agent-forwarding"}, "extension")
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"permit-pty": ""}, values)
+	})
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/api"
+	"path"
+)
+
+// TokenCreationPaths are the creation paths a response-wrapped token is trusted from.
+var TokenCreationPaths = []string{"auth/token/create", "auth/token/create-orphan", "auth/token/create/*"}
+
+// SecretIDCreationPaths are the creation paths a response-wrapped AppRole secret ID is trusted from.
+var SecretIDCreationPaths = []string{"auth/*/role/*/secret-id"}
+
+// UnwrapToken unwraps the given response-wrapping token and returns the Vault token it contains. The wrapping token
+// must have been created at one of the TokenCreationPaths.
+func (c *VaultClient) UnwrapToken(wrappingToken string) (string, error) {
+	secret, err := c.Unwrap(wrappingToken, TokenCreationPaths)
+	if err != nil {
+		return "", err
+	}
+
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return "", fmt.Errorf("no token was found in the wrapped response")
+	}
+
+	return secret.Auth.ClientToken, nil
+}
+
+// UnwrapSecretID unwraps the given response-wrapping token and returns the AppRole secret ID it contains. The wrapping
+// token must have been created at one of the SecretIDCreationPaths.
+func (c *VaultClient) UnwrapSecretID(wrappingToken string) (string, error) {
+	secret, err := c.Unwrap(wrappingToken, SecretIDCreationPaths)
+	if err != nil {
+		return "", err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("no secret id was found in the wrapped response")
+	}
+
+	secretID, ok := secret.Data["secret_id"].(string)
+	if !ok || secretID == "" {
+		return "", fmt.Errorf("no secret id was found in the wrapped response")
+	}
+
+	return secretID, nil
+}
+
+// Unwrap unwraps the given response-wrapping token and returns the secret it contains. The wrapping token is looked up
+// first and is only unwrapped if it was created at a path matching one of the given creation paths (see path.Match),
+// since a wrapping token from an unexpected path may have been intercepted and replaced. The token configured for the
+// underlying API client is left untouched and the request is made in the auth namespace if one has been set.
+func (c *VaultClient) Unwrap(wrappingToken string, creationPaths []string) (*api.Secret, error) {
+	var secret *api.Secret
+	err := c.inNamespace(c.authNamespace, func() error {
+		lookup, err := c.api.Logical().Write("sys/wrapping/lookup", map[string]interface{}{
+			"token": wrappingToken,
+		})
+		if err != nil {
+			return fmt.Errorf("error looking up wrapping token: %w", err)
+		}
+
+		if lookup == nil || lookup.Data == nil {
+			return fmt.Errorf("no details were returned for the wrapping token")
+		}
+
+		creationPath, _ := lookup.Data["creation_path"].(string)
+		if !matchesAnyPath(creationPath, creationPaths) {
+			return fmt.Errorf("wrapping token was created at unexpected path %q", creationPath)
+		}
+
+		// The wrapping token authenticates the unwrap request itself so it is never set on the underlying API client
+		r := c.api.NewRequest("PUT", "/v1/sys/wrapping/unwrap")
+		r.ClientToken = wrappingToken
+
+		resp, err := c.api.RawRequest(r)
+		if resp != nil {
+			defer resp.Body.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("error unwrapping token: %w", err)
+		}
+
+		secret, err = api.ParseSecret(resp.Body)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// matchesAnyPath returns true if the given path matches any of the given patterns.
+func matchesAnyPath(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, p); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
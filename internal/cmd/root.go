@@ -2,26 +2,76 @@
 package cmd
 
 import (
+	"crypto/ed25519"
 	"fmt"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/token"
 	"github.com/jmgilman/vssh/auth"
 	"github.com/jmgilman/vssh/client"
+	"github.com/jmgilman/vssh/internal/storage"
 	"github.com/jmgilman/vssh/internal/ui"
 	"github.com/jmgilman/vssh/ssh"
+	"github.com/jmgilman/vssh/tokenhelper"
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 var server string
-var token string
+var vaultToken string
 var role string
 var mount string
 var persist bool
 var identity string
 var onlySign bool
+var roleID string
+var roleIDFile string
+var secretID string
+var secretIDFile string
+var nonInteractive bool
+var kubernetesRole string
+var kubernetesTokenPath string
+var kubernetesMount string
+var caCert string
+var clientCert string
+var clientKey string
+var tlsServerName string
+var tlsSkipVerify bool
+var certRole string
+var gitHubTokenFile string
+var authMethod string
+var authDetails []string
+var mfaPasscode string
+var minTokenTTL time.Duration
+var tokenRenewThreshold time.Duration
+var ephemeralToken bool
+var namespace string
+var authNamespace string
+var sshNamespace string
+var wrappedToken string
+var wrappedTokenFile string
+var wrappedSecretID string
+var wrappedSecretIDFile string
+var vaultAgentAddress string
+var identities []string
+var allIdentities bool
+var minCertTTL string
+var clockSkew time.Duration
+var principals []string
+var certTTL time.Duration
+var keyID string
+var extensions []string
+var criticalOptions []string
+var remoteUserPrincipal bool
+var useAgent bool
+var ephemeralKey bool
 
 var cfgFile string
 
@@ -45,44 +95,149 @@ automatically prompt to authenticate against Vault and obtain a new token via an
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		main(args)
+		main(cmd, args)
 	},
 }
 
 // main is executed by the root command and is the main entry point to the program
-func main(args []string) {
-	publicKeyPath, pubKeyBytes, err := ssh.GetPublicKey(viper.GetString("identity"))
+func main(cmd *cobra.Command, args []string) {
+	// Ephemeral key-pairs only exist in memory, and the certificate can only outlive ssh when it is given to ssh-agent
+	ephemeral := viper.GetBool("ephemeral_key")
+	if ephemeral && onlySign && !viper.GetBool("agent") {
+		fmt.Println("Signing an ephemeral key-pair without connecting requires --agent")
+		os.Exit(1)
+	}
+
+	var keyPairs []string
+	if !ephemeral {
+		var err error
+		keyPairs, err = getIdentities()
+		if err != nil {
+			errorThenExit("Error finding ssh key-pair", err)
+		}
+	}
+
+	vaultClient, err := client.NewDefaultClientWithAgent(&api.TLSConfig{
+		CACert:        expandPath(viper.GetString("ca_cert")),
+		ClientCert:    expandPath(viper.GetString("client_cert")),
+		ClientKey:     expandPath(viper.GetString("client_key")),
+		TLSServerName: viper.GetString("tls_server_name"),
+		Insecure:      viper.GetBool("tls_skip_verify"),
+	}, viper.GetString("vault_agent_address"))
+	if err != nil {
+		errorThenExit("Error trying to load Vault client configuration", err)
+	}
+
+	if err := vaultClient.SetConfigValues(viper.GetString("server"), viper.GetString("token")); err != nil {
+		errorThenExit("Error setting Vault server or token: ", err)
+	}
+
+	// The auth and SSH namespaces fall back to the general namespace when not set
+	vaultClient.SetNamespace(viper.GetString("namespace"))
+	vaultClient.SetAuthNamespace(viper.GetString("auth_namespace"))
+	vaultClient.SetSSHNamespace(viper.GetString("ssh_namespace"))
+
+	// Existing certs are checked against the CA of the mount, although they are still used if it is unavailable. The CA
+	// key is only fetched, at most once, when a cert passes every other check so Vault isn't contacted needlessly.
+	var caKey []byte
+	caKeyFetched := false
+	getCAKey := func() []byte {
+		if !caKeyFetched {
+			caKeyFetched = true
+			key, err := vaultClient.GetCAPublicKey(viper.GetString("mount"))
+			if err != nil {
+				fmt.Println("Warning: unable to fetch the CA public key, existing certificates will not be checked against it:", err)
+			}
+			caKey = key
+		}
+		return caKey
+	}
+
+	var host string
+	if len(args) > 0 {
+		_, host = ssh.ParseDestination(args[0])
+	}
+
+	signOptions, err := getSignOptions(cmd.Flags(), host)
 	if err != nil {
-		errorThenExit("Error fetching public key", err)
+		errorThenExit("Error reading signing options", err)
+	}
+
+	// A user given explicitly for ssh to login as must be one of the principals of the cert, otherwise the role decides
+	if viper.GetBool("remote_user_principal") {
+		remoteUser := ssh.GetRemoteUser(args)
+		if remoteUser != "" && !contains(signOptions.ValidPrincipals, remoteUser) {
+			signOptions.ValidPrincipals = append(signOptions.ValidPrincipals, remoteUser)
+		}
 	}
 
-	// Check if a cert exists and is still valid
+	// Check if a cert exists and is still valid for each key-pair, only signing those without one
 	// This should be skipped if the user specifically requested signing
-	certPath := ssh.GetPublicKeyCertPath(publicKeyPath)
-	if _, err := os.Stat(certPath); !os.IsNotExist(err) && !onlySign {
-		cert, err := ssh.GetCertificate(certPath)
+	var unsigned []string
+	for _, keyPair := range keyPairs {
+		publicKeyPath, pubKeyBytes, err := ssh.GetPublicKey(keyPair)
 		if err != nil {
-			errorThenExit("Error reading certificate at " + certPath, err)
+			errorThenExit("Error fetching public key", err)
 		}
 
-		if ssh.IsCertificateValid(cert) {
-			runSSH(args) // No need to continue further since the cert is still valid
+		if onlySign || !hasValidCertificate(ssh.GetPublicKeyCertPath(publicKeyPath), pubKeyBytes, getCAKey, signOptions) {
+			unsigned = append(unsigned, keyPair)
 		}
 	}
 
+	if len(unsigned) == 0 && !ephemeral {
+		if viper.GetBool("agent") {
+			addIdentitiesToAgent(keyPairs)
+		}
+		runSSH(args) // No need to continue further since the certs are still valid
+	}
+
 	// Must have a role specified at this point
 	if viper.GetString("role") == "" {
 		fmt.Println("Please specify a role to sign with")
 		os.Exit(1)
 	}
 
-	vaultClient, err := client.NewDefaultClient()
+	helper, err := tokenhelper.NewHelper()
 	if err != nil {
-		errorThenExit("Error trying to load Vault client configuration", err)
+		errorThenExit("Error loading Vault token helper", err)
 	}
 
-	if err := vaultClient.SetConfigValues(viper.GetString("server"), viper.GetString("token")); err != nil {
-		errorThenExit("Error setting Vault server or token: ", err)
+	// A response-wrapped token is unwrapped and used in place of any token supplied directly
+	wrappingToken, err := getSecretValue("wrapped_token")
+	if err != nil {
+		errorThenExit("Error reading wrapped token", err)
+	}
+
+	if wrappingToken != "" {
+		unwrappedToken, err := vaultClient.UnwrapToken(wrappingToken)
+		if err != nil {
+			errorThenExit("Error unwrapping token", err)
+		}
+
+		if err := vaultClient.SetConfigValues("", unwrappedToken); err != nil {
+			errorThenExit("Error setting Vault token", err)
+		}
+
+		// The wrapping token can only be used once so the token must be persisted now if it is to be used again
+		if viper.GetBool("persist") {
+			if err := helper.Store(unwrappedToken); err != nil {
+				errorThenExit("Error persisting token to "+helper.Path(), err)
+			}
+		}
+	}
+
+	// Fall back to the token stored by the Vault token helper (i.e. ~/.vault-token) if one was not given. A Vault Agent
+	// authenticates requests itself, so a stored token would only override its auto-auth token.
+	if vaultClient.Token() == "" && !vaultClient.UsingAgent() {
+		storedToken, err := helper.Get()
+		if err != nil {
+			errorThenExit("Error reading token from token helper", err)
+		}
+
+		if err := vaultClient.SetConfigValues("", strings.TrimSpace(storedToken)); err != nil {
+			errorThenExit("Error setting Vault token", err)
+		}
 	}
 
 	// Verify the vault is in a usable state
@@ -96,59 +251,724 @@ func main(args []string) {
 		os.Exit(1)
 	}
 
-	if !vaultClient.Authenticated() {
-		login(vaultClient)
+	// Tokens which are about to expire are renewed if possible, otherwise a new one is obtained before signing
+	loggedIn := false
+	if !vaultClient.UsingAgent() && !vaultClient.AuthenticatedWithTTL(viper.GetDuration("min_token_ttl"), viper.GetDuration("token_renew_threshold")) {
+		authType, profile := selectAuthType()
+		login(vaultClient, helper, authType, profile)
+		loggedIn = true
 	}
 
-	signedKey, err := vaultClient.SignPubKey(viper.GetString("mount"), viper.GetString("role"), pubKeyBytes)
-	if err != nil {
-		errorThenExit("Error signing public key", err)
+	// From here on, a token obtained by logging in must be revoked before exiting if requested, even on errors
+	exitAfterLogin := func(message string, err error) {
+		revokeEphemeralToken(vaultClient, loggedIn)
+		errorThenExit(message, err)
+	}
+
+	// Check the token may sign with the role before trying to, offering to login with a different authentication
+	// method (which may carry different policies) if it can't
+	for {
+		err := vaultClient.CheckSignCapability(viper.GetString("mount"), viper.GetString("role"))
+		if err == nil {
+			break
+		}
+
+		capErr, ok := err.(*client.CapabilityError)
+		if !ok {
+			exitAfterLogin("Error checking signing capability", err)
+		}
+
+		fmt.Println(capErr.Error())
+		if viper.GetBool("non_interactive") || vaultClient.UsingAgent() {
+			revokeEphemeralToken(vaultClient, loggedIn)
+			os.Exit(1)
+		}
+
+		if _, err := ui.NewConfirmPrompt("Login with a different authentication method").Run(); err != nil {
+			revokeEphemeralToken(vaultClient, loggedIn)
+			os.Exit(1)
+		}
+
+		// A token obtained by logging in above is replaced by the next login, so it must be revoked now or never
+		if loggedIn {
+			if err := vaultClient.RevokeToken(); err != nil {
+				fmt.Println("Warning: failed to revoke token without signing capability:", err)
+			}
+		}
+
+		authType, profile := chooseAuthType(loadAuthProfiles())
+		login(vaultClient, helper, authType, profile)
+		loggedIn = true
+	}
+
+	for _, keyPair := range unsigned {
+		publicKeyPath, pubKeyBytes, err := ssh.GetPublicKey(keyPair)
+		if err != nil {
+			exitAfterLogin("Error fetching public key", err)
+		}
+
+		signedKey, err := vaultClient.SignPubKeyWithOptions(viper.GetString("mount"), viper.GetString("role"), pubKeyBytes, signOptions)
+		if err != nil {
+			exitAfterLogin("Error signing public key", err)
+		}
+
+		certPath := ssh.GetPublicKeyCertPath(publicKeyPath)
+		if err := storage.WriteFile(certPath, []byte(signedKey), 0644); err != nil {
+			exitAfterLogin("Error writing public key certificate", err)
+		}
+
+		fmt.Println("Wrote certificate to ", certPath)
+	}
+
+	var generatedKey *ed25519.PrivateKey
+	var generatedCert string
+	if ephemeral {
+		generatedKey, generatedCert, err = signEphemeralKey(vaultClient, signOptions)
+		if err != nil {
+			exitAfterLogin("Error signing ephemeral key-pair", err)
+		}
 	}
 
-	if err := ioutil.WriteFile(certPath, []byte(signedKey), 0644); err != nil {
-		errorThenExit("Error writing public key certificate", err)
+	revokeEphemeralToken(vaultClient, loggedIn)
+
+	if viper.GetBool("agent") && !ephemeral {
+		addIdentitiesToAgent(keyPairs)
+	}
+
+	if ephemeral {
+		if keyPath, cleanup := storeEphemeralKey(generatedKey, generatedCert); keyPath != "" {
+			runSSHWithTemporaryIdentity(args, keyPath, cleanup)
+		}
 	}
 
-	fmt.Println("Wrote certificate to ", certPath)
 	if !onlySign {
 		runSSH(args)
 	}
 }
 
+// revokeEphemeralToken revokes the current token if it was obtained by logging in (as given) and ephemeral_token is set
+// without persist. Tokens supplied by the user or the token helper are never revoked.
+func revokeEphemeralToken(vaultClient *client.VaultClient, loggedIn bool) {
+	if !loggedIn || !viper.GetBool("ephemeral_token") || viper.GetBool("persist") {
+		return
+	}
+
+	if err := vaultClient.RevokeToken(); err != nil {
+		fmt.Println("Warning: failed to revoke ephemeral token:", err)
+	}
+}
+
 // login performs the process of requesting credentials from the end-user and using them to perform a login against the
-// given VaultClient instance.
-func login(vaultClient *client.VaultClient) {
-	// Ask which authentication type they would like to use
-	prompt := ui.NewSelectPrompt("Please choose an authentication method:", auth.GetAuthNames())
-	_, result, err := prompt.Run()
-	if err != nil {
-		fmt.Println("Error getting authentication method:", err)
-		os.Exit(1)
+// given VaultClient instance with the given authentication type and the profile it was created from, if any. If
+// requested, the obtained token is persisted with the given token helper.
+func login(vaultClient *client.VaultClient, helper token.TokenHelper, authType auth.Auth, profile *auth.Profile) {
+	// Collect authentication details for the selected method, only prompting for those not supplied ahead of time
+	details := authType.AuthDetails()
+	if profile != nil {
+		profile.SetDefaults(details)
 	}
 
-	// Collect authentication details for the selected method
-	authType := auth.Types[result]()
-	details, err := ui.GetAuthDetails(authType, ui.NewPrompt)
+	name := authType.Name()
+	if profile != nil {
+		name = profile.Name
+	}
+
+	if err := setDefaultAuthDetails(name, details); err != nil {
+		errorThenExit("Error reading authentication details", err)
+	}
+
+	if err := setAuthDetailValues(details, authDetailKeys[authType.Name()]); err != nil {
+		errorThenExit("Error reading authentication details", err)
+	}
+
+	if err := setWrappedSecretID(vaultClient, details); err != nil {
+		errorThenExit("Error unwrapping secret id", err)
+	}
+
+	if err := setAuthDetailFlags(details); err != nil {
+		errorThenExit("Error reading authentication details", err)
+	}
+
+	prompterFactory := ui.NewPrompt
+	if viper.GetBool("non_interactive") {
+		prompterFactory = ui.NewNonInteractivePrompt
+	}
+
+	if err := ui.FillAuthDetails(details, prompterFactory); err != nil {
+		errorThenExit("Error collecting authentication details", err)
+	}
 
 	// Login with the collected details
-	if err := vaultClient.Login(authType, details); err != nil {
+	err := vaultClient.Login(authType, details)
+	if mfaErr, ok := err.(*client.MFARequiredError); ok {
+		err = validateMFA(vaultClient, mfaErr.Requirement, prompterFactory)
+	}
+
+	if err != nil {
 		fmt.Println("Error logging in:", err)
 		os.Exit(1)
 	}
 
 	fmt.Println("Authentication successful!")
+	if !viper.GetBool("non_interactive") {
+		writeLastAuthMethod(name)
+	}
 
 	if viper.GetBool("persist") {
-		home, err := homedir.Dir()
+		if err := helper.Store(vaultClient.Token()); err != nil {
+			errorThenExit("Error persisting token to "+helper.Path(), err)
+		}
+	}
+}
+
+// authDetailKeys maps the name of an authentication type to the configuration keys which supply its details, keyed by
+// detail name.
+var authDetailKeys = map[string]map[string]string{
+	auth.NewAppRoleAuth().Name(): {
+		"role_id":   "role_id",
+		"secret_id": "secret_id",
+	},
+	auth.NewKubernetesAuth().Name(): {
+		"role": "kubernetes_role",
+	},
+	auth.NewGitHubAuth().Name(): {
+		"token": "github_token",
+	},
+}
+
+// validateMFA answers the given MFA requirement using the passcode supplied via flags or by prompting the end-user.
+func validateMFA(vaultClient *client.VaultClient, requirement *client.MFARequirement, prompterFactory func(string, bool) ui.Prompter) error {
+	fmt.Println("Multi-factor authentication is required to complete the login")
+	payload, err := ui.GetMFAPayload(requirement, viper.GetString("mfa_passcode"), prompterFactory)
+	if err != nil {
+		return err
+	}
+
+	return vaultClient.ValidateMFA(requirement.RequestID, payload)
+}
+
+// selectAuthType returns the authentication type to login with along with the profile it was created from, if any.
+// AppRole is used without prompting whenever a role ID has been supplied, Kubernetes whenever a Kubernetes role has been
+// supplied, and Cert whenever a certificate role has been supplied. If a default authentication method is configured it
+// is used without prompting, otherwise the end-user is asked to choose from the authentication methods in the config
+// file or, if none are configured, every supported authentication type.
+func selectAuthType() (auth.Auth, *auth.Profile) {
+	if viper.GetString("role_id") != "" || viper.GetString("role_id_file") != "" {
+		return auth.NewAppRoleAuth(), nil
+	}
+
+	if viper.GetString("kubernetes_role") != "" {
+		return newKubernetesAuth(""), nil
+	}
+
+	if viper.GetString("cert_role") != "" {
+		return auth.NewCertAuthWithConfig("cert", viper.GetString("cert_role")), nil
+	}
+
+	profiles := loadAuthProfiles()
+	if method := viper.GetString("auth_method"); method != "" {
+		authType, profile, err := findAuthMethod(method, profiles)
 		if err != nil {
-			errorThenExit("Error getting user home directory", err)
+			errorThenExit("Error loading authentication method", err)
+		}
+
+		return authType, profile
+	}
+
+	if viper.GetBool("non_interactive") {
+		fmt.Println("No token or machine credentials were supplied and prompting is disabled in non-interactive mode")
+		os.Exit(1)
+	}
+
+	return chooseAuthType(profiles)
+}
+
+// loadAuthProfiles returns the authentication methods configured in the config file.
+func loadAuthProfiles() []auth.Profile {
+	var profiles []auth.Profile
+	if err := viper.UnmarshalKey("auth_methods", &profiles); err != nil {
+		errorThenExit("Error reading authentication methods from config", err)
+	}
+
+	return profiles
+}
+
+// chooseAuthType asks the end-user to choose from the given authentication methods or, if none are given, every
+// supported authentication type. It returns the chosen authentication type along with the profile it was created from,
+// if any.
+func chooseAuthType(profiles []auth.Profile) (auth.Auth, *auth.Profile) {
+	// Ask which authentication type they would like to use, starting from the one used last
+	items, err := getAuthMethodItems(profiles)
+	if err != nil {
+		errorThenExit("Error loading authentication methods", err)
+	}
+
+	cursor := 0
+	lastUsed := readLastAuthMethod()
+	for i, item := range items {
+		if item.Name == lastUsed {
+			cursor = i
+		}
+	}
+
+	prompt := ui.NewDescribedSelectPrompt("Please choose an authentication method:", items, cursor)
+	i, _, err := prompt.Run()
+	if err != nil {
+		fmt.Println("Error getting authentication method:", err)
+		os.Exit(1)
+	}
+
+	authType, profile, err := findAuthMethod(items[i].Name, profiles)
+	if err != nil {
+		errorThenExit("Error loading authentication method", err)
+	}
+
+	return authType, profile
+}
+
+// getAuthMethodItems returns the authentication methods to offer the end-user. If any profiles are configured they are
+// offered in the order they were configured, otherwise every supported authentication type is offered.
+func getAuthMethodItems(profiles []auth.Profile) ([]ui.SelectItem, error) {
+	var items []ui.SelectItem
+	if len(profiles) > 0 {
+		for _, profile := range profiles {
+			description := profile.Description
+			if description == "" {
+				authType, err := profile.NewAuth()
+				if err != nil {
+					return nil, err
+				}
+				description = authType.Description()
+			}
+
+			items = append(items, ui.SelectItem{Name: profile.Name, Description: description})
+		}
+
+		return items, nil
+	}
+
+	for _, name := range auth.GetAuthNames() {
+		items = append(items, ui.SelectItem{Name: name, Description: auth.Types[name]().Description()})
+	}
+
+	return items, nil
+}
+
+// lastAuthMethodPath returns the path to the file used to remember the last authentication method used.
+func lastAuthMethodPath() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".vssh_last_auth"), nil
+}
+
+// readLastAuthMethod returns the name of the last authentication method used to successfully login, or an empty
+// string if it is unknown.
+func readLastAuthMethod() string {
+	path, err := lastAuthMethodPath()
+	if err != nil {
+		return ""
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// writeLastAuthMethod remembers the name of the given authentication method so it can be preselected next time.
+func writeLastAuthMethod(name string) {
+	path, err := lastAuthMethodPath()
+	if err == nil {
+		err = storage.WriteFile(path, []byte(name+"\n"), 0600)
+	}
+
+	if err != nil {
+		fmt.Println("Unable to remember authentication method:", err)
+	}
+}
+
+// findAuthMethod returns the authentication type with the given name. Configured profiles take precedence over the
+// supported authentication types and names are matched case-insensitively.
+func findAuthMethod(name string, profiles []auth.Profile) (auth.Auth, *auth.Profile, error) {
+	for i := range profiles {
+		if strings.EqualFold(profiles[i].Name, name) {
+			authType, err := profiles[i].NewAuth()
+			if _, ok := authType.(*auth.KubernetesAuth); ok {
+				authType = newKubernetesAuth(profiles[i].Mount)
+			}
+			return authType, &profiles[i], err
+		}
+	}
+
+	for typeName, factory := range auth.Types {
+		if strings.EqualFold(typeName, name) {
+			authType := factory()
+			if _, ok := authType.(*auth.KubernetesAuth); ok {
+				authType = newKubernetesAuth("")
+			}
+			return authType, nil, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no authentication method named %q", name)
+}
+
+// newKubernetesAuth returns a KubernetesAuth which reads the service account token from the configured path. It logs in
+// against the given mount or, if it is empty, the configured Kubernetes mount.
+func newKubernetesAuth(mount string) auth.Auth {
+	if mount == "" {
+		mount = viper.GetString("kubernetes_mount")
+	}
+
+	return auth.NewKubernetesAuthWithConfig(mount, viper.GetString("kubernetes_token_path"))
+}
+
+// setDefaultAuthDetails fills in any of the given details which have a default value configured for the authentication
+// method with the given name. Defaults are read from the auth_details section of the config file, keyed by method name,
+// and then from environment variables named after the detail (i.e. $VSSH_AUTH_DETAIL_USERNAME).
+func setDefaultAuthDetails(name string, details map[string]*auth.Detail) error {
+	for method, values := range viper.GetStringMap("auth_details") {
+		if !strings.EqualFold(method, name) {
+			continue
+		}
+
+		defaults, err := cast.ToStringMapStringE(values)
+		if err != nil {
+			return fmt.Errorf("invalid auth_details for %q: %w", name, err)
+		}
+
+		for detailName, value := range defaults {
+			if detail, ok := details[detailName]; ok {
+				detail.Value = value
+			}
+		}
+	}
+
+	for detailName, detail := range details {
+		if value := viper.GetString("auth_detail_" + detailName); value != "" {
+			detail.Value = value
+		}
+	}
+
+	return nil
+}
+
+// setAuthDetailFlags fills in any of the given details which were supplied with the --auth-detail flag in the form
+// of name=value.
+func setAuthDetailFlags(details map[string]*auth.Detail) error {
+	for _, pair := range authDetails {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid auth detail %q - must be in the form of name=value", pair)
+		}
+
+		if detail, ok := details[parts[0]]; ok {
+			detail.Value = parts[1]
+		}
+	}
+
+	return nil
+}
+
+// setAuthDetailValues fills in any of the given details which have been supplied via flags, environment variables,
+// files, or the config file using the given map of detail names to configuration keys. Details which are left without
+// a value will be prompted for.
+func setAuthDetailValues(details map[string]*auth.Detail, keys map[string]string) error {
+	for name, key := range keys {
+		detail, ok := details[name]
+		if !ok {
+			continue
+		}
+
+		value, err := getSecretValue(key)
+		if err != nil {
+			return err
 		}
 
-		tokenPath := filepath.Join(home, ".vault-token")
-		if err := ioutil.WriteFile(tokenPath, []byte(vaultClient.Token()), 0644); err != nil {
-			errorThenExit("Error persising token to ~/.vault-token", err)
+		if value != "" {
+			detail.Value = value
 		}
 	}
+
+	return nil
+}
+
+// setWrappedSecretID sets the secret_id detail, if present and not already set, to the AppRole secret ID contained in
+// the configured response-wrapping token.
+func setWrappedSecretID(vaultClient *client.VaultClient, details map[string]*auth.Detail) error {
+	detail, ok := details["secret_id"]
+	if !ok || detail.Value != nil {
+		return nil
+	}
+
+	wrappingToken, err := getSecretValue("wrapped_secret_id")
+	if err != nil || wrappingToken == "" {
+		return err
+	}
+
+	secretID, err := vaultClient.UnwrapSecretID(wrappingToken)
+	if err != nil {
+		return err
+	}
+
+	detail.Value = secretID
+	return nil
+}
+
+// getSecretValue returns the configured value for the given key. If the key itself is not set, it falls back to
+// reading the contents of the file configured by the key suffixed with _file (i.e. secret_id_file).
+func getSecretValue(key string) (string, error) {
+	if value := viper.GetString(key); value != "" {
+		return value, nil
+	}
+
+	path := viper.GetString(key + "_file")
+	if path == "" {
+		return "", nil
+	}
+
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// expandPath expands a leading ~ in the given path to the user's home directory. The path is returned unmodified if it
+// cannot be expanded.
+func expandPath(path string) string {
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return path
+	}
+
+	return expanded
+}
+
+// getSignOptions returns the options to request when signing a public key for connecting to the given host. Options
+// set with flags or environment variables take precedence over those of the first host_defaults entry matching the
+// host, which in turn take precedence over those set in the config file. Extensions and critical options given with
+// flags are added to the configured ones.
+func getSignOptions(flags *pflag.FlagSet, host string) (*client.SignOptions, error) {
+	opts := &client.SignOptions{
+		ValidPrincipals: viper.GetStringSlice("principals"),
+		TTL:             viper.GetDuration("cert_ttl"),
+		KeyID:           viper.GetString("key_id"),
+		Extensions:      viper.GetStringMapString("extensions"),
+		CriticalOptions: viper.GetStringMapString("critical_options"),
+	}
+
+	var defaults []client.HostDefaults
+	if err := viper.UnmarshalKey("host_defaults", &defaults); err != nil {
+		return nil, err
+	}
+
+	matched, err := client.MatchHostDefaults(defaults, host)
+	if err != nil {
+		return nil, err
+	}
+	if matched != nil {
+		opts.ApplyDefaults(matched.SignOptions, map[string]bool{
+			"principals": setExplicitly(flags, "principals", "principals"),
+			"cert_ttl":   setExplicitly(flags, "cert_ttl", "cert-ttl"),
+			"key_id":     setExplicitly(flags, "key_id", "key-id"),
+		})
+	}
+
+	if opts.Extensions, err = client.MergeKeyValues(opts.Extensions, extensions, "extension"); err != nil {
+		return nil, err
+	}
+	if opts.CriticalOptions, err = client.MergeKeyValues(opts.CriticalOptions, criticalOptions, "critical option"); err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+// setExplicitly returns true if the given configuration key was set with its flag or its environment variable.
+func setExplicitly(flags *pflag.FlagSet, key string, flag string) bool {
+	if flags.Changed(flag) {
+		return true
+	}
+
+	_, ok := os.LookupEnv("VSSH_" + strings.ToUpper(key))
+	return ok
+}
+
+// getIdentities returns the paths to the ssh key-pairs to sign. A configured identity is always used, otherwise the
+// configured identities are searched for and the first one found is returned, or every one found if all_identities is
+// set.
+func getIdentities() ([]string, error) {
+	if configured := viper.GetString("identity"); configured != "" {
+		return []string{expandPath(configured)}, nil
+	}
+
+	searched := viper.GetStringSlice("identities")
+	for i := range searched {
+		searched[i] = expandPath(searched[i])
+	}
+
+	keyPairs, err := ssh.FindIdentities(searched)
+	if err != nil {
+		return []string{}, err
+	}
+
+	if len(keyPairs) == 0 {
+		return []string{}, fmt.Errorf("none of the key-pairs %s have a public key", strings.Join(searched, ", "))
+	}
+
+	if !viper.GetBool("all_identities") {
+		return keyPairs[:1], nil
+	}
+
+	return keyPairs, nil
+}
+
+// hasValidCertificate returns true if a certificate exists at the given path, remains valid for at least the configured
+// minimum ttl, matches the given signing options (see ssh.CheckCertificateOptions) and was issued for the given public
+// key by the CA whose public key is returned by the given function (see ssh.CheckCertificate). The CA public key is only
+// requested once every other check has passed.
+func hasValidCertificate(certPath string, publicKey []byte, getCAKey func() []byte, signOptions *client.SignOptions) bool {
+	if _, err := os.Stat(certPath); os.IsNotExist(err) {
+		return false
+	}
+
+	cert, err := ssh.GetCertificate(certPath)
+	if err != nil {
+		errorThenExit("Error reading certificate at " + certPath, err)
+	}
+
+	if err := ssh.CheckCertificate(cert, publicKey, nil); err != nil {
+		fmt.Println("Replacing certificate at", certPath+":", err)
+		return false
+	}
+
+	if err := ssh.CheckCertificateOptions(cert, signOptions.ValidPrincipals, signOptions.KeyID, signOptions.Extensions,
+		signOptions.CriticalOptions); err != nil {
+		fmt.Println("Replacing certificate at", certPath+":", err)
+		return false
+	}
+
+	minTTL, err := ssh.GetMinTTL(cert, viper.GetString("min_cert_ttl"))
+	if err != nil {
+		errorThenExit("Error reading minimum certificate ttl", err)
+	}
+
+	if !ssh.IsCertificateValidFor(cert, minTTL, viper.GetDuration("clock_skew")) {
+		return false
+	}
+
+	if err := ssh.CheckCertificate(cert, publicKey, getCAKey()); err != nil {
+		fmt.Println("Replacing certificate at", certPath+":", err)
+		return false
+	}
+
+	return true
+}
+
+// addIdentitiesToAgent adds each of the given ssh key-pairs to the running ssh-agent along with its certificate, which
+// must already exist. Key-pairs whose certificate is already in the agent are skipped and the rest are added as described
+// by ssh.AddIdentityToAgent.
+func addIdentitiesToAgent(keyPairs []string) {
+	sshAgent, conn, err := ssh.ConnectAgent()
+	if err != nil {
+		errorThenExit("Error connecting to ssh-agent", err)
+	}
+	defer conn.Close()
+
+	for _, keyPair := range keyPairs {
+		publicKeyPath, err := ssh.GetPublicKeyPath(keyPair)
+		if err != nil {
+			errorThenExit("Error fetching public key", err)
+		}
+
+		cert, err := ssh.GetCertificate(ssh.GetPublicKeyCertPath(publicKeyPath))
+		if err != nil {
+			errorThenExit("Error reading certificate", err)
+		}
+
+		added, err := ssh.AgentHasCertificate(sshAgent, cert)
+		if err != nil {
+			errorThenExit("Error listing ssh-agent keys", err)
+		}
+		if added {
+			continue
+		}
+
+		if err := ssh.AddIdentityToAgent(sshAgent, keyPair, cert); err != nil {
+			errorThenExit("Error adding certificate to ssh-agent", err)
+		}
+	}
+}
+
+// signEphemeralKey generates a new ed25519 key-pair in memory and signs it with the given options, returning the private
+// key along with its signed certificate.
+func signEphemeralKey(vaultClient *client.VaultClient, signOptions *client.SignOptions) (*ed25519.PrivateKey, string, error) {
+	key, pubKeyBytes, err := ssh.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	signedKey, err := vaultClient.SignPubKeyWithOptions(viper.GetString("mount"), viper.GetString("role"), pubKeyBytes, signOptions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, signedKey, nil
+}
+
+// storeEphemeralKey hands the given ephemeral private key and its signed certificate to ssh. When using ssh-agent, they
+// are only added to the agent and an empty identity is returned. Otherwise they are written to a temporary identity
+// which is returned along with the function which removes it.
+func storeEphemeralKey(key *ed25519.PrivateKey, signedKey string) (string, func() error) {
+	if !viper.GetBool("agent") {
+		keyPath, cleanup, err := ssh.WriteTemporaryIdentity(key, []byte(signedKey))
+		if err != nil {
+			errorThenExit("Error writing ephemeral key-pair", err)
+		}
+
+		return keyPath, cleanup
+	}
+
+	cert, err := ssh.ParseCertificate([]byte(signedKey))
+	if err != nil {
+		errorThenExit("Error reading signed certificate", err)
+	}
+
+	sshAgent, conn, err := ssh.ConnectAgent()
+	if err != nil {
+		errorThenExit("Error connecting to ssh-agent", err)
+	}
+	defer conn.Close()
+
+	if err := ssh.AddToAgent(sshAgent, key, cert, "vssh ephemeral key"); err != nil {
+		errorThenExit("Error adding certificate to ssh-agent", err)
+	}
+
+	fmt.Println("Added ephemeral certificate to ssh-agent")
+	return "", nil
+}
+
+// contains returns true if the given slice contains the given value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
 }
 
 // runSSH creates and executes the ssh command using the given arguments
@@ -160,6 +980,15 @@ func runSSH(args []string) {
 	os.Exit(0)
 }
 
+// runSSHWithTemporaryIdentity runs ssh with the given temporary identity, which is removed with the given function once
+// the ssh process exits (see ssh.RunWithTemporaryIdentity).
+func runSSHWithTemporaryIdentity(args []string, keyPath string, cleanup func() error) {
+	if err := ssh.RunWithTemporaryIdentity(args, keyPath, cleanup); err != nil {
+		errorThenExit("Error running ssh command", err)
+	}
+	os.Exit(0)
+}
+
 // errorThenExit is a small wrapper for reporting and error and existing with a non-zero exit code
 func errorThenExit(message string, err error) {
 	fmt.Println(message, ":", err)
@@ -184,7 +1013,10 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&server, "server", "s", "", "address of vault server (default: $VAULT_ADDR)")
 	err := viper.BindPFlag("server", rootCmd.PersistentFlags().Lookup("server"))
 
-	rootCmd.PersistentFlags().StringVarP(&token, "token", "t", "", "vault token to use for authentication (default: $VAULT_TOKEN)")
+	rootCmd.PersistentFlags().StringVarP(&vaultAgentAddress, "vault-agent-address", "", "", "address of a vault agent to send all requests through, may be a unix:// socket (default: $VAULT_AGENT_ADDR)")
+	err = viper.BindPFlag("vault_agent_address", rootCmd.PersistentFlags().Lookup("vault-agent-address"))
+
+	rootCmd.PersistentFlags().StringVarP(&vaultToken, "token", "t", "", "vault token to use for authentication (default: $VAULT_TOKEN)")
 	err = viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
 
 	rootCmd.PersistentFlags().StringVarP(&role, "role", "r", "", "vault role account to sign with")
@@ -193,13 +1025,135 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&mount, "mount", "m", "", "mount path for ssh backend (default: ssh)")
 	err = viper.BindPFlag("mount", rootCmd.PersistentFlags().Lookup("mount"))
 
-	rootCmd.PersistentFlags().BoolVarP(&persist, "persist", "p", false, "persist obtained tokens to ~/.vault-token")
+	rootCmd.PersistentFlags().StringVarP(&wrappedToken, "wrapped-token", "", "", "response-wrapping token containing the vault token to use (default: $VSSH_WRAPPED_TOKEN)")
+	err = viper.BindPFlag("wrapped_token", rootCmd.PersistentFlags().Lookup("wrapped-token"))
+
+	rootCmd.PersistentFlags().StringVarP(&wrappedTokenFile, "wrapped-token-file", "", "", "file containing the response-wrapping token for the vault token to use")
+	err = viper.BindPFlag("wrapped_token_file", rootCmd.PersistentFlags().Lookup("wrapped-token-file"))
+
+	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "", "", "vault enterprise namespace to use for all requests (default: $VAULT_NAMESPACE)")
+	err = viper.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
+
+	rootCmd.PersistentFlags().StringVarP(&authNamespace, "auth-namespace", "", "", "vault enterprise namespace to login in (default: --namespace)")
+	err = viper.BindPFlag("auth_namespace", rootCmd.PersistentFlags().Lookup("auth-namespace"))
+
+	rootCmd.PersistentFlags().StringVarP(&sshNamespace, "ssh-namespace", "", "", "vault enterprise namespace the ssh backend is mounted in (default: --namespace)")
+	err = viper.BindPFlag("ssh_namespace", rootCmd.PersistentFlags().Lookup("ssh-namespace"))
+
+	rootCmd.PersistentFlags().DurationVarP(&minTokenTTL, "min-token-ttl", "", time.Minute, "minimum remaining token ttl before a new token is obtained")
+	err = viper.BindPFlag("min_token_ttl", rootCmd.PersistentFlags().Lookup("min-token-ttl"))
+
+	rootCmd.PersistentFlags().DurationVarP(&tokenRenewThreshold, "token-renew-threshold", "", 5*time.Minute, "remaining token ttl below which renewable tokens are renewed")
+	err = viper.BindPFlag("token_renew_threshold", rootCmd.PersistentFlags().Lookup("token-renew-threshold"))
+
+	rootCmd.PersistentFlags().BoolVarP(&ephemeralToken, "ephemeral-token", "e", false, "revoke tokens obtained by logging in once the public key is signed (ignored with --persist)")
+	err = viper.BindPFlag("ephemeral_token", rootCmd.PersistentFlags().Lookup("ephemeral-token"))
+
+	rootCmd.PersistentFlags().BoolVarP(&persist, "persist", "p", false, "persist obtained tokens with the vault token helper (default: ~/.vault-token)")
 	err = viper.BindPFlag("persist", rootCmd.PersistentFlags().Lookup("persist"))
 
+	// Authentication variables
+	rootCmd.PersistentFlags().StringVarP(&authMethod, "auth-method", "a", "", "name of the authentication method to login with instead of prompting")
+	err = viper.BindPFlag("auth_method", rootCmd.PersistentFlags().Lookup("auth-method"))
+
+	rootCmd.PersistentFlags().StringArrayVarP(&authDetails, "auth-detail", "", []string{}, "value for an authentication detail in the form of name=value (i.e. username=jdoe)")
+
+	rootCmd.PersistentFlags().StringVarP(&mfaPasscode, "mfa-passcode", "", "", "passcode to answer a multi-factor authentication challenge with (default: $VSSH_MFA_PASSCODE)")
+	err = viper.BindPFlag("mfa_passcode", rootCmd.PersistentFlags().Lookup("mfa-passcode"))
+
+	// AppRole variables
+	rootCmd.PersistentFlags().StringVarP(&roleID, "role-id", "", "", "approle role id to login with (default: $VSSH_ROLE_ID)")
+	err = viper.BindPFlag("role_id", rootCmd.PersistentFlags().Lookup("role-id"))
+
+	rootCmd.PersistentFlags().StringVarP(&roleIDFile, "role-id-file", "", "", "file containing the approle role id to login with")
+	err = viper.BindPFlag("role_id_file", rootCmd.PersistentFlags().Lookup("role-id-file"))
+
+	rootCmd.PersistentFlags().StringVarP(&secretID, "secret-id", "", "", "approle secret id to login with (default: $VSSH_SECRET_ID)")
+	err = viper.BindPFlag("secret_id", rootCmd.PersistentFlags().Lookup("secret-id"))
+
+	rootCmd.PersistentFlags().StringVarP(&secretIDFile, "secret-id-file", "", "", "file containing the approle secret id to login with")
+	err = viper.BindPFlag("secret_id_file", rootCmd.PersistentFlags().Lookup("secret-id-file"))
+
+	rootCmd.PersistentFlags().StringVarP(&wrappedSecretID, "wrapped-secret-id", "", "", "response-wrapping token containing the approle secret id to login with (default: $VSSH_WRAPPED_SECRET_ID)")
+	err = viper.BindPFlag("wrapped_secret_id", rootCmd.PersistentFlags().Lookup("wrapped-secret-id"))
+
+	rootCmd.PersistentFlags().StringVarP(&wrappedSecretIDFile, "wrapped-secret-id-file", "", "", "file containing the response-wrapping token for the approle secret id to login with")
+	err = viper.BindPFlag("wrapped_secret_id_file", rootCmd.PersistentFlags().Lookup("wrapped-secret-id-file"))
+
+	rootCmd.PersistentFlags().BoolVarP(&nonInteractive, "non-interactive", "n", false, "never prompt for input - fail if any details are missing")
+	err = viper.BindPFlag("non_interactive", rootCmd.PersistentFlags().Lookup("non-interactive"))
+
+	// Kubernetes variables
+	rootCmd.PersistentFlags().StringVarP(&kubernetesRole, "kubernetes-role", "", "", "kubernetes auth role to login with using the pod service account")
+	err = viper.BindPFlag("kubernetes_role", rootCmd.PersistentFlags().Lookup("kubernetes-role"))
+
+	rootCmd.PersistentFlags().StringVarP(&kubernetesTokenPath, "kubernetes-token-path", "", "", "path to the service account token (default: "+auth.DefaultKubernetesTokenPath+")")
+	err = viper.BindPFlag("kubernetes_token_path", rootCmd.PersistentFlags().Lookup("kubernetes-token-path"))
+
+	rootCmd.PersistentFlags().StringVarP(&kubernetesMount, "kubernetes-mount", "", "kubernetes", "mount path for the kubernetes auth backend")
+	err = viper.BindPFlag("kubernetes_mount", rootCmd.PersistentFlags().Lookup("kubernetes-mount"))
+
+	// TLS variables
+	rootCmd.PersistentFlags().StringVarP(&caCert, "ca-cert", "", "", "path to a PEM CA certificate to verify the vault server with (default: $VAULT_CACERT)")
+	err = viper.BindPFlag("ca_cert", rootCmd.PersistentFlags().Lookup("ca-cert"))
+
+	rootCmd.PersistentFlags().StringVarP(&clientCert, "client-cert", "", "", "path to a PEM client certificate for TLS authentication (default: $VAULT_CLIENT_CERT)")
+	err = viper.BindPFlag("client_cert", rootCmd.PersistentFlags().Lookup("client-cert"))
+
+	rootCmd.PersistentFlags().StringVarP(&clientKey, "client-key", "", "", "path to the private key for the client certificate (default: $VAULT_CLIENT_KEY)")
+	err = viper.BindPFlag("client_key", rootCmd.PersistentFlags().Lookup("client-key"))
+
+	rootCmd.PersistentFlags().StringVarP(&tlsServerName, "tls-server-name", "", "", "name to use as the SNI host when connecting to the vault server")
+	err = viper.BindPFlag("tls_server_name", rootCmd.PersistentFlags().Lookup("tls-server-name"))
+
+	rootCmd.PersistentFlags().BoolVarP(&tlsSkipVerify, "tls-skip-verify", "", false, "disable verification of the vault server certificate")
+	err = viper.BindPFlag("tls_skip_verify", rootCmd.PersistentFlags().Lookup("tls-skip-verify"))
+
+	rootCmd.PersistentFlags().StringVarP(&certRole, "cert-role", "", "", "cert auth role to login with using the client certificate")
+	err = viper.BindPFlag("cert_role", rootCmd.PersistentFlags().Lookup("cert-role"))
+
+	// GitHub variables - the token itself is only read from $VSSH_GITHUB_TOKEN or the config file to keep it out of the
+	// process arguments
+	rootCmd.PersistentFlags().StringVarP(&gitHubTokenFile, "github-token-file", "", "", "file containing the github personal access token to login with")
+	err = viper.BindPFlag("github_token_file", rootCmd.PersistentFlags().Lookup("github-token-file"))
+
 	// SSH variables
-	rootCmd.PersistentFlags().StringVarP(&identity, "identity", "i", "", "ssh key-pair to sign and use (default: $HOME/.ssh/id_rsa)")
+	rootCmd.PersistentFlags().StringVarP(&identity, "identity", "i", "", "ssh key-pair to sign and use (default: first of --identities found)")
 	err = viper.BindPFlag("identity", rootCmd.PersistentFlags().Lookup("identity"))
 
+	rootCmd.PersistentFlags().StringSliceVarP(&identities, "identities", "", ssh.DefaultIdentities, "ssh key-pairs to search for in order when no identity is given, names are relative to $HOME/.ssh")
+	err = viper.BindPFlag("identities", rootCmd.PersistentFlags().Lookup("identities"))
+
+	rootCmd.PersistentFlags().BoolVarP(&allIdentities, "all-identities", "", false, "sign every key-pair found from --identities instead of only the first")
+	err = viper.BindPFlag("all_identities", rootCmd.PersistentFlags().Lookup("all-identities"))
+
+	rootCmd.PersistentFlags().StringSliceVarP(&principals, "principals", "", []string{}, "principals to request in the certificate (default: the role's default user)")
+	err = viper.BindPFlag("principals", rootCmd.PersistentFlags().Lookup("principals"))
+
+	rootCmd.PersistentFlags().DurationVarP(&certTTL, "cert-ttl", "", 0, "ttl to request for the certificate (default: the role's ttl)")
+	err = viper.BindPFlag("cert_ttl", rootCmd.PersistentFlags().Lookup("cert-ttl"))
+
+	rootCmd.PersistentFlags().StringVarP(&keyID, "key-id", "", "", "key id to request for the certificate, the role must allow user key ids")
+	err = viper.BindPFlag("key_id", rootCmd.PersistentFlags().Lookup("key-id"))
+
+	rootCmd.PersistentFlags().StringArrayVarP(&extensions, "extension", "", []string{}, "extension to request in the form of name[=value] (i.e. permit-port-forwarding), replaces the role's default extensions")
+	rootCmd.PersistentFlags().StringArrayVarP(&criticalOptions, "critical-option", "", []string{}, "critical option to request in the form of name=value (i.e. source-address=10.0.0.0/8)")
+
+	rootCmd.PersistentFlags().BoolVarP(&remoteUserPrincipal, "remote-user-principal", "", true, "add the user given with the destination, -l or the ssh config to the requested principals and re-sign certificates without it")
+	err = viper.BindPFlag("remote_user_principal", rootCmd.PersistentFlags().Lookup("remote-user-principal"))
+
+	rootCmd.PersistentFlags().BoolVarP(&useAgent, "agent", "", false, "add the key-pair and its certificate to the running ssh-agent ($SSH_AUTH_SOCK) until the certificate expires")
+	err = viper.BindPFlag("agent", rootCmd.PersistentFlags().Lookup("agent"))
+
+	rootCmd.PersistentFlags().BoolVarP(&ephemeralKey, "ephemeral-key", "", false, "sign a new key-pair generated in memory which is only given to ssh-agent with --agent or to ssh for this session")
+	err = viper.BindPFlag("ephemeral_key", rootCmd.PersistentFlags().Lookup("ephemeral-key"))
+
+	rootCmd.PersistentFlags().StringVarP(&minCertTTL, "min-cert-ttl", "", "1m", "minimum remaining certificate lifetime as a duration or percentage of its lifetime (i.e. 20%) before it is re-signed")
+	err = viper.BindPFlag("min_cert_ttl", rootCmd.PersistentFlags().Lookup("min-cert-ttl"))
+
+	rootCmd.PersistentFlags().DurationVarP(&clockSkew, "clock-skew", "", time.Minute, "clock skew tolerated when checking a certificate is already valid")
+	err = viper.BindPFlag("clock_skew", rootCmd.PersistentFlags().Lookup("clock-skew"))
+
 	rootCmd.PersistentFlags().BoolVarP(&onlySign, "only-sign", "", false, "only sign the public key - do not execute ssh process")
 	err = viper.BindPFlag("sign", rootCmd.PersistentFlags().Lookup("only-sign"))
 
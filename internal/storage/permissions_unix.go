@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package storage
+
+import (
+	"os"
+	"syscall"
+)
+
+// isOwner returns whether the file described by the given info is owned by the current user.
+func isOwner(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+
+	return stat.Uid == uint32(os.Getuid())
+}
+
+// isPrivate returns whether the file described by the given info is inaccessible to the group and other users.
+func isPrivate(info os.FileInfo) bool {
+	return info.Mode().Perm()&0077 == 0
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package storage
+
+import (
+	"os"
+)
+
+// isOwner always returns true on Windows where file ownership is managed through ACLs instead of a owning user ID.
+func isOwner(info os.FileInfo) bool {
+	return true
+}
+
+// isPrivate always returns true on Windows where access is managed through ACLs instead of permission bits.
+func isPrivate(info os.FileInfo) bool {
+	return true
+}
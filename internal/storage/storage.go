@@ -0,0 +1,108 @@
+// The storage package provides safe access to files on disk which hold credentials or certificates. Writes are atomic
+// so that a file is never left partially written, and secrets are checked for correct ownership and permissions before
+// they are read.
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// SecretPerm is the file mode used for files which contain secrets such as tokens.
+const SecretPerm os.FileMode = 0600
+
+// Warnings is where warnings about insecure secret files are written when they are read.
+var Warnings io.Writer = os.Stderr
+
+// PermissionError is returned when a secret file is owned by another user or is accessible by other users.
+type PermissionError struct {
+	Path         string
+	Mode         os.FileMode
+	ForeignOwner bool
+}
+
+// Error returns a description of the error.
+func (e *PermissionError) Error() string {
+	if e.ForeignOwner {
+		return fmt.Sprintf("%s is not owned by the current user", e.Path)
+	}
+	return fmt.Sprintf("%s has permissions %#o and is accessible by other users - it should be %#o", e.Path,
+		e.Mode.Perm(), SecretPerm)
+}
+
+// WriteFile atomically writes the given data to the file at the given path with the given permissions. The data is
+// written to a temporary file in the same directory which then replaces the file at the given path, so readers only
+// ever see the previous or the new contents.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	// The temporary file is only left behind if something failed before the rename
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteSecretFile atomically writes the given data to the file at the given path so that it is only accessible by
+// the current user.
+func WriteSecretFile(path string, data []byte) error {
+	return WriteFile(path, data, SecretPerm)
+}
+
+// CheckSecretFile verifies the file at the given path is owned by the current user and is not accessible by any other
+// users. A *PermissionError is returned if either check fails.
+func CheckSecretFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !isOwner(info) {
+		return &PermissionError{Path: path, Mode: info.Mode(), ForeignOwner: true}
+	}
+
+	if !isPrivate(info) {
+		return &PermissionError{Path: path, Mode: info.Mode()}
+	}
+
+	return nil
+}
+
+// ReadSecretFile reads the secret file at the given path. Reading a file owned by another user is refused, while a
+// file accessible by other users is read after writing a warning to Warnings.
+func ReadSecretFile(path string) ([]byte, error) {
+	if err := CheckSecretFile(path); err != nil {
+		permErr, ok := err.(*PermissionError)
+		if !ok || permErr.ForeignOwner {
+			return nil, err
+		}
+
+		fmt.Fprintf(Warnings, "Warning: %s\n", permErr)
+	}
+
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func newTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "id_rsa-cert.pub")
+
+	t.Run("With new file", func(t *testing.T) {
+		assert.Nil(t, WriteFile(path, []byte("first"), 0644))
+		data, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "first", string(data))
+	})
+	t.Run("With existing file", func(t *testing.T) {
+		assert.Nil(t, WriteFile(path, []byte("second"), 0644))
+		data, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "second", string(data))
+	})
+	t.Run("Without leftover temporary files", func(t *testing.T) {
+		files, err := ioutil.ReadDir(dir)
+		assert.Nil(t, err)
+		assert.Len(t, files, 1)
+	})
+	t.Run("With missing directory", func(t *testing.T) {
+		assert.NotNil(t, WriteFile(filepath.Join(dir, "missing", "file"), []byte("data"), 0644))
+	})
+}
+
+func TestWriteSecretFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not used on windows")
+	}
+
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".vault-token")
+
+	// An existing world readable file should be replaced with a private one
+	if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, WriteSecretFile(path, []byte("token")))
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SecretPerm, info.Mode().Perm())
+	assert.Nil(t, CheckSecretFile(path))
+}
+
+func TestReadSecretFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not used on windows")
+	}
+
+	dir := newTempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".vault-token")
+
+	var warnings bytes.Buffer
+	Warnings = &warnings
+	defer func() { Warnings = os.Stderr }()
+
+	t.Run("With private file", func(t *testing.T) {
+		warnings.Reset()
+		if err := WriteSecretFile(path, []byte("token")); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ReadSecretFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "token", string(data))
+		assert.Empty(t, warnings.String())
+	})
+	t.Run("With world readable file", func(t *testing.T) {
+		warnings.Reset()
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := CheckSecretFile(path)
+		assert.IsType(t, &PermissionError{}, err)
+
+		data, err := ReadSecretFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, "token", string(data))
+		assert.Contains(t, warnings.String(), path)
+	})
+	t.Run("With missing file", func(t *testing.T) {
+		_, err := ReadSecretFile(filepath.Join(dir, "missing"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
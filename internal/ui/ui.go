@@ -2,7 +2,10 @@
 package ui
 
 import (
+	"fmt"
+	"strings"
 	"github.com/jmgilman/vssh/auth"
+	"github.com/jmgilman/vssh/client"
 	"github.com/manifoldco/promptui"
 )
 
@@ -27,6 +30,31 @@ func NewPrompt(message string, hidden bool) Prompter {
 	}
 }
 
+// NewConfirmPrompt returns a promptui.Prompt which asks the end-user a yes or no question with the given message. Running
+// the prompt returns an error unless the end-user answers yes.
+func NewConfirmPrompt(message string) Prompter {
+	return &promptui.Prompt{
+		Label:     message,
+		IsConfirm: true,
+	}
+}
+
+// nonInteractivePrompt is a Prompter which never asks the end-user for input and instead fails with an error.
+type nonInteractivePrompt struct {
+	message string
+}
+
+// Run always returns an error indicating the value for the prompt message was not supplied.
+func (p *nonInteractivePrompt) Run() (string, error) {
+	return "", fmt.Errorf("no value supplied for %q and prompting is disabled in non-interactive mode", p.message)
+}
+
+// NewNonInteractivePrompt returns a Prompter which fails instead of asking for input. It shares the signature of
+// NewPrompt so that it can be given to GetAuthDetails when running without an end-user (i.e. in CI).
+func NewNonInteractivePrompt(message string, hidden bool) Prompter {
+	return &nonInteractivePrompt{message: message}
+}
+
 // NewSelectPrompt returns a promptui.SelectPrompt with its prompt message configured to the given message and the
 // available options for the user to select configured to the given string slice.
 func NewSelectPrompt(message string, options []string) *promptui.Select {
@@ -36,20 +64,99 @@ func NewSelectPrompt(message string, options []string) *promptui.Select {
 	}
 }
 
+// SelectItem represents an option presented to the end-user in a select prompt along with a short description of it.
+type SelectItem struct {
+	Name        string
+	Description string
+}
+
+// NewDescribedSelectPrompt returns a promptui.SelectPrompt with its prompt message configured to the given message and
+// the available options configured to the given items, each displayed with its description. The cursor starts on the
+// item at the given position and the items can be searched by name or description by pressing /.
+func NewDescribedSelectPrompt(message string, items []SelectItem, cursorPos int) *promptui.Select {
+	size := len(items)
+	if size > 10 {
+		size = 10
+	}
+
+	return &promptui.Select{
+		Label:     message,
+		Items:     items,
+		Size:      size,
+		CursorPos: cursorPos,
+		Templates: &promptui.SelectTemplates{
+			Active:   "\U000025B8 {{ .Name | cyan }} {{ .Description | faint }}",
+			Inactive: "  {{ .Name }} {{ .Description | faint }}",
+			Selected: "\U000025B8 {{ .Name }}",
+		},
+		Searcher: func(input string, index int) bool {
+			item := items[index]
+			content := strings.ToLower(item.Name + " " + item.Description)
+			return strings.Contains(content, strings.ToLower(strings.TrimSpace(input)))
+		},
+	}
+}
+
 // GetAuthDetails retrieves the authentication details from the given authentication type and proceeds to prompt the
 // user to provide input for each of the retrieved details. It returns the detail map configured with the input data
 // from the end-user.
 func GetAuthDetails(a auth.Auth, prompterFactory func(message string, hidden bool) Prompter) (map[string]*auth.Detail, error) {
 	details := a.AuthDetails()
+	if err := FillAuthDetails(details, prompterFactory); err != nil {
+		return map[string]*auth.Detail{}, err
+	}
+
+	return details, nil
+}
+
+// FillAuthDetails prompts the user to provide input for every detail in the given map which does not already have a
+// value. This allows details supplied ahead of time (i.e. from flags or configuration) to skip being prompted for.
+func FillAuthDetails(details map[string]*auth.Detail, prompterFactory func(message string, hidden bool) Prompter) error {
 	for _, detail := range details {
+		if detail.Value != nil {
+			continue
+		}
+
 		prompt := prompterFactory(detail.Prompt, detail.Hidden)
 		result, err := prompt.Run()
 		if err != nil {
-			return map[string]*auth.Detail{}, err
+			return err
 		}
 
 		detail.Value = result
 	}
 
-	return details, nil
-}
\ No newline at end of file
+	return nil
+}
+
+// GetMFAPayload builds the payload for answering the given MFA requirement. The first method of each constraint is used
+// and, if it requires a passcode, the given passcode is used or the end-user is prompted for one if it is empty.
+// Methods which do not use a passcode (i.e. push notifications) are answered with an empty list.
+func GetMFAPayload(r *client.MFARequirement, passcode string, prompterFactory func(message string, hidden bool) Prompter) (map[string][]string, error) {
+	payload := map[string][]string{}
+	for name, constraint := range r.Constraints {
+		if len(constraint.Any) == 0 {
+			return map[string][]string{}, fmt.Errorf("no methods available for mfa constraint %q", name)
+		}
+
+		method := constraint.Any[0]
+		if !method.UsesPasscode {
+			payload[method.ID] = []string{}
+			continue
+		}
+
+		value := passcode
+		if value == "" {
+			prompt := prompterFactory(fmt.Sprintf("Passcode (%s): ", method.Type), true)
+			result, err := prompt.Run()
+			if err != nil {
+				return map[string][]string{}, err
+			}
+			value = result
+		}
+
+		payload[method.ID] = []string{value}
+	}
+
+	return payload, nil
+}
@@ -2,6 +2,7 @@ package ui_test
 
 import (
 	"github.com/jmgilman/vssh/auth"
+	"github.com/jmgilman/vssh/client"
 	"github.com/jmgilman/vssh/internal/mocks"
 	"github.com/jmgilman/vssh/internal/ui"
 	"github.com/manifoldco/promptui"
@@ -19,6 +20,28 @@ func TestGetAuthDetails2(t *testing.T) {
 	assert.Equal(t, expected.Items, got.Items)
 }
 
+func TestNewConfirmPrompt(t *testing.T) {
+	got := ui.NewConfirmPrompt("test").(*promptui.Prompt)
+	assert.Equal(t, "test", got.Label)
+	assert.True(t, got.IsConfirm)
+}
+
+func TestNewDescribedSelectPrompt(t *testing.T) {
+	items := []ui.SelectItem{
+		{Name: "Userpass", Description: "Username and password"},
+		{Name: "OIDC", Description: "Single sign-on"},
+	}
+	got := ui.NewDescribedSelectPrompt("test", items, 1)
+	assert.Equal(t, "test", got.Label)
+	assert.Equal(t, items, got.Items)
+	assert.Equal(t, 1, got.CursorPos)
+
+	// Searching should match against both the name and description
+	assert.True(t, got.Searcher("user", 0))
+	assert.True(t, got.Searcher("sign-on", 1))
+	assert.False(t, got.Searcher("sign-on", 0))
+}
+
 func TestGetAuthDetails(t *testing.T) {
 	var messages []string
 	expectedMessages := []string{"Field1", "Field2"}
@@ -58,4 +81,87 @@ func TestGetAuthDetails(t *testing.T) {
 	// Assert that the return from Run() was put back into the details struct
 	assert.Equal(t, "test", details["field1"].Value)
 	assert.Equal(t, "test", details["field2"].Value)
-}
\ No newline at end of file
+}
+func TestFillAuthDetails(t *testing.T) {
+	var messages []string
+	prompter := func(message string, hidden bool) ui.Prompter {
+		messages = append(messages, message)
+		return &mocks.PrompterMock{
+			RunFunc: func() (string, error) {
+				return "prompted", nil
+			},
+		}
+	}
+	details := map[string]*auth.Detail{
+		"field1": {
+			Value:  "supplied",
+			Prompt: "Field1",
+		},
+		"field2": {
+			Prompt: "Field2",
+			Hidden: true,
+		},
+	}
+
+	if err := ui.FillAuthDetails(details, prompter); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert that only the missing detail was prompted for
+	assert.Equal(t, []string{"Field2"}, messages)
+	assert.Equal(t, "supplied", details["field1"].Value)
+	assert.Equal(t, "prompted", details["field2"].Value)
+}
+
+func TestNewNonInteractivePrompt(t *testing.T) {
+	details := map[string]*auth.Detail{
+		"field1": {
+			Value:  "supplied",
+			Prompt: "Field1",
+		},
+		"field2": {
+			Prompt: "Field2",
+		},
+	}
+
+	err := ui.FillAuthDetails(details, ui.NewNonInteractivePrompt)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Field2")
+}
+
+func TestGetMFAPayload(t *testing.T) {
+	requirement := &client.MFARequirement{
+		RequestID: "request",
+		Constraints: map[string]client.MFAConstraint{
+			"totp": {Any: []client.MFAMethod{{Type: "totp", ID: "totp-id", UsesPasscode: true}}},
+			"duo":  {Any: []client.MFAMethod{{Type: "duo", ID: "duo-id", UsesPasscode: false}}},
+		},
+	}
+
+	t.Run("With prompted passcode", func(t *testing.T) {
+		var messages []string
+		prompter := func(message string, hidden bool) ui.Prompter {
+			messages = append(messages, message)
+			return &mocks.PrompterMock{
+				RunFunc: func() (string, error) {
+					return "123456", nil
+				},
+			}
+		}
+
+		payload, err := ui.GetMFAPayload(requirement, "", prompter)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Passcode (totp): "}, messages)
+		assert.Equal(t, []string{"123456"}, payload["totp-id"])
+		assert.Equal(t, []string{}, payload["duo-id"])
+	})
+	t.Run("With supplied passcode", func(t *testing.T) {
+		payload, err := ui.GetMFAPayload(requirement, "654321", ui.NewNonInteractivePrompt)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"654321"}, payload["totp-id"])
+	})
+	t.Run("Without passcode in non-interactive mode", func(t *testing.T) {
+		_, err := ui.GetMFAPayload(requirement, "", ui.NewNonInteractivePrompt)
+		assert.NotNil(t, err)
+	})
+}
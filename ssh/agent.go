@@ -0,0 +1,163 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	cssh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// ConnectAgent connects to the ssh-agent listening at $SSH_AUTH_SOCK. The returned connection should be closed once the
+// agent is no longer needed.
+func ConnectAgent() (agent.ExtendedAgent, net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, fmt.Errorf("SSH_AUTH_SOCK is not set - is ssh-agent running?")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return agent.NewClient(conn), conn, nil
+}
+
+// NewSSHAddCommand returns a exec.Cmd type preconfigured to run the ssh-add binary using the given args and with all
+// standard inputs/outputs configured to redirect the process to the end-user, so it can prompt for passphrases.
+func NewSSHAddCommand(args []string) *exec.Cmd {
+	c := exec.Command("ssh-add", args...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Stdin = os.Stdin
+	return c
+}
+
+// AddToAgent adds the given private key along with the given SSH certificate to the given agent. The entry expires
+// when the certificate does, and any entries for certificates of the same key which expire no later than it (i.e.
+// expired certificates) are replaced.
+func AddToAgent(a agent.Agent, key interface{}, cert *cssh.Certificate, comment string) error {
+	lifetime, err := getLifetime(cert)
+	if err != nil {
+		return err
+	}
+
+	if err := removeReplacedCertificates(a, cert); err != nil {
+		return err
+	}
+
+	return a.Add(agent.AddedKey{
+		PrivateKey:   key,
+		Certificate:  cert,
+		Comment:      comment,
+		LifetimeSecs: lifetime,
+	})
+}
+
+// AddIdentityToAgent adds the private key at the given path along with its SSH certificate to the given agent as
+// described by AddToAgent. Private keys which can't be loaded here, such as encrypted keys and security keys, are added
+// with ssh-add instead, which prompts for their passphrase and also adds the private key by itself. In that case the
+// agent must be the one listening at $SSH_AUTH_SOCK and the certificate must be at the path returned by
+// GetPublicKeyCertPath.
+func AddIdentityToAgent(a agent.Agent, identity string, cert *cssh.Certificate) error {
+	data, err := ioutil.ReadFile(identity)
+	if err != nil {
+		return err
+	}
+
+	key, err := cssh.ParseRawPrivateKey(data)
+	if err != nil {
+		return addIdentityWithSSHAdd(a, identity, cert)
+	}
+
+	// The agent only accepts ed25519 keys by reference
+	if k, ok := key.(ed25519.PrivateKey); ok {
+		key = &k
+	}
+
+	return AddToAgent(a, key, cert, identity)
+}
+
+// AgentHasCertificate returns whether the given SSH certificate has been added to the given agent.
+func AgentHasCertificate(a agent.Agent, cert *cssh.Certificate) (bool, error) {
+	entries, err := a.List()
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if bytes.Equal(entry.Blob, cert.Marshal()) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// addIdentityWithSSHAdd adds the private key at the given path along with its SSH certificate to the agent listening at
+// $SSH_AUTH_SOCK using ssh-add. Entries of the given agent are replaced as described by AddToAgent.
+func addIdentityWithSSHAdd(a agent.Agent, identity string, cert *cssh.Certificate) error {
+	lifetime, err := getLifetime(cert)
+	if err != nil {
+		return err
+	}
+
+	if err := removeReplacedCertificates(a, cert); err != nil {
+		return err
+	}
+
+	var args []string
+	if lifetime > 0 {
+		args = append(args, "-t", strconv.FormatUint(uint64(lifetime), 10))
+	}
+
+	return NewSSHAddCommand(append(args, identity)).Run()
+}
+
+// getLifetime returns the number of seconds until the given SSH certificate expires, or zero if it never does.
+func getLifetime(cert *cssh.Certificate) (uint32, error) {
+	if cert.ValidBefore == cssh.CertTimeInfinity {
+		return 0, nil
+	}
+
+	remaining := int64(cert.ValidBefore) - time.Now().Unix()
+	if remaining <= 0 {
+		return 0, fmt.Errorf("certificate has already expired")
+	}
+
+	return uint32(remaining), nil
+}
+
+// removeReplacedCertificates removes the entries of the given agent for certificates of the same key as the given SSH
+// certificate which expire no later than it.
+func removeReplacedCertificates(a agent.Agent, cert *cssh.Certificate) error {
+	entries, err := a.List()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		existing, err := cssh.ParsePublicKey(entry.Blob)
+		if err != nil {
+			continue
+		}
+
+		c, ok := existing.(*cssh.Certificate)
+		if !ok || !bytes.Equal(c.Key.Marshal(), cert.Key.Marshal()) || c.ValidBefore > cert.ValidBefore {
+			continue
+		}
+
+		if err := a.Remove(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
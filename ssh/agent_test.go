@@ -0,0 +1,220 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	cssh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingAgent is an in-process agent which records the keys added to it.
+type recordingAgent struct {
+	agent.Agent
+	added []agent.AddedKey
+}
+
+func (r *recordingAgent) Add(key agent.AddedKey) error {
+	r.added = append(r.added, key)
+	return r.Agent.Add(key)
+}
+
+// newTestAgent returns a client connected to a new in-process agent along with the agent itself.
+func newTestAgent(t *testing.T) (agent.ExtendedAgent, *recordingAgent) {
+	keyring := &recordingAgent{Agent: agent.NewKeyring()}
+	clientConn, serverConn := net.Pipe()
+	go agent.ServeAgent(keyring, serverConn)
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+
+	return agent.NewClient(clientConn), keyring
+}
+
+// newTestCert returns a user certificate for the given key signed by the given CA which is valid until validBefore.
+func newTestCert(t *testing.T, key cssh.PublicKey, ca cssh.Signer, validBefore time.Time) *cssh.Certificate {
+	cert := &cssh.Certificate{
+		Key:         key,
+		CertType:    cssh.UserCert,
+		ValidAfter:  uint64(time.Now().Add(-time.Hour).Unix()),
+		ValidBefore: uint64(validBefore.Unix()),
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestAddIdentityToAgent(t *testing.T) {
+	ca, _ := newTestKey(t)
+	key, publicKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, cleanup, err := WriteTemporaryIdentity(key, []byte("cert"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	parsed, _, _, _, err := cssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := newTestCert(t, parsed, ca, time.Now().Add(10*time.Minute))
+
+	client, keyring := newTestAgent(t)
+	assert.Nil(t, AddIdentityToAgent(client, identity, cert))
+
+	result, err := AgentHasCertificate(client, cert)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	// Only the certificate is added when the private key is loaded without ssh-add
+	if assert.Len(t, keyring.added, 1) {
+		assert.Equal(t, identity, keyring.added[0].Comment)
+		assert.InDelta(t, 600, keyring.added[0].LifetimeSecs, 5)
+	}
+}
+
+func TestAddIdentityToAgent_Encrypted(t *testing.T) {
+	for _, name := range []string{"ssh-add", "ssh-keygen"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skip(name + " is not installed")
+		}
+	}
+
+	tempDir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Serve an in-process agent on a socket so ssh-add can reach it
+	keyring := &recordingAgent{Agent: agent.NewKeyring()}
+	socket := filepath.Join(tempDir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	// Encrypted keys in the openssh-key-v1 format can only be loaded by ssh-add
+	identity := filepath.Join(tempDir, "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "secret", "-C", "test", "-f", identity).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	askPass := filepath.Join(tempDir, "askpass")
+	if err := ioutil.WriteFile(askPass, []byte("#!/bin/sh\necho secret\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SSH_ASKPASS", askPass)
+	t.Setenv("SSH_ASKPASS_REQUIRE", "force")
+	t.Setenv("DISPLAY", ":0")
+
+	publicKey, err := ioutil.ReadFile(identity + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, _, _, err := cssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, _ := newTestKey(t)
+	cert := newTestCert(t, key, ca, time.Now().Add(10*time.Minute))
+	if err := ioutil.WriteFile(identity+"-cert.pub", cssh.MarshalAuthorizedKey(cert), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, conn, err := ConnectAgent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	assert.Nil(t, AddIdentityToAgent(client, identity, cert))
+
+	result, err := AgentHasCertificate(client, cert)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	// ssh-add adds the private key by itself as well as with its certificate
+	if assert.Len(t, keyring.added, 2) {
+		for _, added := range keyring.added {
+			assert.InDelta(t, 600, added.LifetimeSecs, 5)
+		}
+	}
+}
+
+func TestAddToAgent(t *testing.T) {
+	ca, _ := newTestKey(t)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := cssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("With new certificate", func(t *testing.T) {
+		client, keyring := newTestAgent(t)
+		cert := newTestCert(t, signer.PublicKey(), ca, time.Now().Add(10*time.Minute))
+
+		assert.Nil(t, AddToAgent(client, &key, cert, "test"))
+		if assert.Len(t, keyring.added, 1) {
+			assert.InDelta(t, 600, keyring.added[0].LifetimeSecs, 5)
+		}
+
+		result, err := AgentHasCertificate(client, cert)
+		assert.Nil(t, err)
+		assert.True(t, result)
+	})
+	t.Run("With expired certificate in agent", func(t *testing.T) {
+		client, _ := newTestAgent(t)
+		expired := newTestCert(t, signer.PublicKey(), ca, time.Now().Add(-time.Minute))
+		if err := client.Add(agent.AddedKey{PrivateKey: &key, Certificate: expired}); err != nil {
+			t.Fatal(err)
+		}
+
+		cert := newTestCert(t, signer.PublicKey(), ca, time.Now().Add(10*time.Minute))
+		assert.Nil(t, AddToAgent(client, &key, cert, "test"))
+
+		result, err := AgentHasCertificate(client, expired)
+		assert.Nil(t, err)
+		assert.False(t, result)
+
+		keys, err := client.List()
+		assert.Nil(t, err)
+		assert.Len(t, keys, 1)
+	})
+	t.Run("With expired certificate", func(t *testing.T) {
+		client, keyring := newTestAgent(t)
+		cert := newTestCert(t, signer.PublicKey(), ca, time.Now().Add(-time.Minute))
+
+		assert.NotNil(t, AddToAgent(client, &key, cert, "test"))
+		assert.Empty(t, keyring.added)
+	})
+}
@@ -0,0 +1,144 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"github.com/jmgilman/vssh/internal/storage"
+	cssh "golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// GenerateKey generates a new ed25519 key-pair in memory and returns its private key along with its public key in the
+// authorized_keys format, ready to be signed.
+func GenerateKey() (*ed25519.PrivateKey, []byte, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := cssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &priv, cssh.MarshalAuthorizedKey(publicKey), nil
+}
+
+// WriteTemporaryIdentity writes the given ed25519 private key and its signed certificate to a new temporary directory
+// which only the current user can access. It returns the path to the private key, which can be passed to ssh with -i,
+// along with a function which removes the directory and everything in it.
+func WriteTemporaryIdentity(key *ed25519.PrivateKey, signedKey []byte) (string, func() error, error) {
+	data, err := marshalED25519PrivateKey(*key, "vssh")
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		return "", nil, err
+	}
+
+	cleanup := func() error {
+		return os.RemoveAll(dir)
+	}
+
+	identity := filepath.Join(dir, "id_ed25519")
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "OPENSSH PRIVATE KEY",
+		Bytes: data,
+	})
+
+	if err := storage.WriteFile(identity, privateKey, storage.SecretPerm); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	if err := storage.WriteFile(identity+"-cert.pub", signedKey, storage.SecretPerm); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return identity, cleanup, nil
+}
+
+// RunWithTemporaryIdentity runs ssh with the given args and identity, which is removed with the given function once the
+// ssh process exits, whether it succeeds or not. Interrupts received in the meantime are ignored, as the terminal already
+// delivers them to ssh, while hangups and terminations are forwarded to ssh, so neither ends this process before the
+// identity can be removed.
+func RunWithTemporaryIdentity(args []string, identity string, cleanup func() error) error {
+	// Interrupts are caught rather than ignored with signal.Ignore, which ssh would inherit
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	cmd := NewSSHCommand(append([]string{"-i", identity}, args...))
+	err := cmd.Start()
+	if err == nil {
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case sig := <-signals:
+					if sig != os.Interrupt {
+						cmd.Process.Signal(sig)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		err = cmd.Wait()
+		close(done)
+	}
+
+	if cleanupErr := cleanup(); cleanupErr != nil {
+		return fmt.Errorf("failed to remove temporary identity %s: %w", identity, cleanupErr)
+	}
+
+	return err
+}
+
+// marshalED25519PrivateKey encodes the given ed25519 private key in the unencrypted openssh-key-v1 format read by ssh.
+// See PROTOCOL.key in the OpenSSH source for details of the format.
+func marshalED25519PrivateKey(key ed25519.PrivateKey, comment string) ([]byte, error) {
+	publicKey := key.Public().(ed25519.PublicKey)
+
+	var check uint32
+	if err := binary.Read(rand.Reader, binary.BigEndian, &check); err != nil {
+		return nil, err
+	}
+
+	private := cssh.Marshal(struct {
+		Check1     uint32
+		Check2     uint32
+		KeyType    string
+		PublicKey  []byte
+		PrivateKey []byte
+		Comment    string
+	}{check, check, cssh.KeyAlgoED25519, publicKey, key, comment})
+
+	// The private section is padded to the cipher block size, which is 8 when unencrypted
+	for i := byte(1); len(private)%8 != 0; i++ {
+		private = append(private, i)
+	}
+
+	return append([]byte("openssh-key-v1\x00"), cssh.Marshal(struct {
+		CipherName  string
+		KdfName     string
+		KdfOpts     string
+		NumKeys     uint32
+		PublicKey   []byte
+		PrivateKeys []byte
+	}{"none", "none", "", 1, cssh.Marshal(struct {
+		KeyType   string
+		PublicKey []byte
+	}{cssh.KeyAlgoED25519, publicKey}), private})...), nil
+}
@@ -0,0 +1,82 @@
+package ssh
+
+import (
+	"github.com/stretchr/testify/assert"
+	cssh "golang.org/x/crypto/ssh"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	key, publicKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := cssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, cssh.MarshalAuthorizedKey(signer.PublicKey()), publicKey)
+}
+
+func TestWriteTemporaryIdentity(t *testing.T) {
+	key, publicKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, cleanup, err := WriteTemporaryIdentity(key, []byte("cert"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{identity, identity + "-cert.pub"} {
+		info, err := os.Stat(path)
+		if assert.Nil(t, err) {
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		}
+	}
+
+	data, err := ioutil.ReadFile(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := cssh.ParsePrivateKey(data)
+	if assert.Nil(t, err) {
+		assert.Equal(t, publicKey, cssh.MarshalAuthorizedKey(signer.PublicKey()))
+	}
+
+	assert.Nil(t, cleanup())
+	_, err = os.Stat(filepath.Dir(identity))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunWithTemporaryIdentity(t *testing.T) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		t.Skip("ssh is not installed")
+	}
+
+	key, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, cleanup, err := WriteTemporaryIdentity(key, []byte("cert"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	// ssh refuses to start with an unknown option
+	err = RunWithTemporaryIdentity([]string{"-o", "NoSuchOption=yes", "localhost"}, identity, cleanup)
+	assert.NotNil(t, err)
+
+	_, err = os.Stat(filepath.Dir(identity))
+	assert.True(t, os.IsNotExist(err))
+}
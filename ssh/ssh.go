@@ -1,16 +1,24 @@
 package ssh
 
 import (
+	"bytes"
 	"fmt"
 	cssh "golang.org/x/crypto/ssh"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DefaultIdentities are the names of the key-pairs in $HOME/.ssh which are searched for, in order, when no identity is
+// given.
+var DefaultIdentities = []string{"id_ed25519", "id_ed25519_sk", "id_ecdsa", "id_ecdsa_sk", "id_rsa"}
+
 // NewSSHCommand returns a exec.Cmd type preconfigured to run the ssh binary using the given args and with all standard
 // inputs/outputs configured to redirect the process to the end-user.
 func NewSSHCommand(args []string) *exec.Cmd {
@@ -21,6 +29,99 @@ func NewSSHCommand(args []string) *exec.Cmd {
 	return c
 }
 
+// ParseDestination takes a ssh destination in the form of [user@]host or ssh://[user@]host[:port] and returns its user
+// and host. The user is empty if the destination does not include one.
+func ParseDestination(destination string) (user string, host string) {
+	if strings.HasPrefix(destination, "ssh://") {
+		u, err := url.Parse(destination)
+		if err == nil {
+			return u.User.Username(), u.Hostname()
+		}
+	}
+
+	if i := strings.LastIndex(destination, "@"); i >= 0 {
+		return destination[:i], destination[i+1:]
+	}
+
+	return "", destination
+}
+
+// GetRemoteUser takes the arguments ssh is run with (i.e. [user@]host followed by any ssh flags) and returns the user
+// ssh will login as when it was given explicitly with the destination, the -l flag or User in the ssh config. The user
+// is resolved by ssh itself using ssh -G, which falls back to the local user when none was given, so the local user is
+// only returned when it was given with the destination or the -l flag. If ssh can't resolve it, the user is taken from
+// the destination or the -l flag instead. An empty string is returned when no user was given.
+func GetRemoteUser(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	output, err := exec.Command("ssh", append([]string{"-G"}, args...)...).Output()
+	if err == nil {
+		if configUser := parseConfigUser(output); configUser != "" && configUser != localUsername() {
+			return configUser
+		}
+	}
+
+	return parseRemoteUser(args)
+}
+
+// localUsername returns the name of the current user, which ssh logs in as when no other user is given.
+func localUsername() string {
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return current.Username
+}
+
+// parseConfigUser returns the user from the output of ssh -G.
+func parseConfigUser(output []byte) string {
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "user" {
+			return fields[1]
+		}
+	}
+
+	return ""
+}
+
+// parseRemoteUser returns the user from the destination or the -l flag of the given ssh arguments.
+func parseRemoteUser(args []string) string {
+	if user, _ := ParseDestination(args[0]); user != "" {
+		return user
+	}
+
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "-l" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(args[i], "-l") && len(args[i]) > 2:
+			return args[i][2:]
+		}
+	}
+
+	return ""
+}
+
+// HasPrincipal returns whether the given SSH certificate may be used to login as the given principal. Certificates
+// without any principals are valid for all of them.
+func HasPrincipal(cert *cssh.Certificate, principal string) bool {
+	if len(cert.ValidPrincipals) == 0 {
+		return true
+	}
+
+	for _, p := range cert.ValidPrincipals {
+		if p == principal {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetPublicKey takes a path to a private key and finds its associated public key, reading it into memory and returning
 // its content in byte form.
 func GetPublicKey(identity string) (string, []byte, error) {
@@ -48,28 +149,81 @@ func GetCertificate(certPath string) (*cssh.Certificate, error) {
 		return &cssh.Certificate{}, err
 	}
 
-	cert, _, _, _, err := cssh.ParseAuthorizedKey(signedKeyBytes)
+	return ParseCertificate(signedKeyBytes)
+}
+
+// ParseCertificate parses the given SSH certificate in the authorized_keys format (i.e. as returned by Vault) and
+// returns it as a ssh.Certificate.
+func ParseCertificate(signedKey []byte) (*cssh.Certificate, error) {
+	key, _, _, _, err := cssh.ParseAuthorizedKey(signedKey)
 	if err != nil {
 		return &cssh.Certificate{}, err
 	}
 
-	return cert.(*cssh.Certificate), nil
+	cert, ok := key.(*cssh.Certificate)
+	if !ok {
+		return &cssh.Certificate{}, fmt.Errorf("key is not a certificate")
+	}
+
+	return cert, nil
 }
 
 // GetPublicKeyPath takes the path to a private key and returns the path to its associated public key. If the given
-// path is empty, it defaults to returning the public key for $HOME/.ssh/id_rsa.
+// path is empty, it defaults to returning the public key for the first of the DefaultIdentities which exists, or
+// $HOME/.ssh/id_ed25519 if none of them do.
 func GetPublicKeyPath(identity string) (publicKeyPath string, err error) {
 	if identity == "" {
-		defaultPubKeyPath, err := os.UserHomeDir()
+		identities, err := FindIdentities(DefaultIdentities)
 		if err != nil {
-			fmt.Println("Failed to get user home directory: ", err)
-			os.Exit(1)
+			return "", err
+		}
+
+		if len(identities) == 0 {
+			identities, err = identityPaths(DefaultIdentities[:1])
+			if err != nil {
+				return "", err
+			}
+		}
+		identity = identities[0]
+	}
+
+	return identity + ".pub", nil
+}
+
+// FindIdentities takes a list of key-pairs and returns the paths to those which have a public key, in the same order.
+// Key-pairs given by name only (i.e. id_ed25519) are looked for in $HOME/.ssh.
+func FindIdentities(identities []string) ([]string, error) {
+	paths, err := identityPaths(identities)
+	if err != nil {
+		return []string{}, err
+	}
+
+	var found []string
+	for _, path := range paths {
+		if _, err := os.Stat(path + ".pub"); err == nil {
+			found = append(found, path)
+		}
+	}
+
+	return found, nil
+}
+
+// identityPaths returns the paths to the given key-pairs, resolving those given by name only against $HOME/.ssh.
+func identityPaths(identities []string) ([]string, error) {
+	var paths []string
+	for _, identity := range identities {
+		if !strings.ContainsRune(identity, filepath.Separator) && !strings.ContainsRune(identity, '/') {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return []string{}, fmt.Errorf("failed to get user home directory: %w", err)
+			}
+			identity = filepath.Join(home, ".ssh", identity)
 		}
-		publicKeyPath = filepath.Join(defaultPubKeyPath, ".ssh/id_rsa.pub")
-	} else {
-		publicKeyPath = identity + ".pub"
+
+		paths = append(paths, identity)
 	}
-	return
+
+	return paths, nil
 }
 
 // GetPublicKeyCertPath takes the path to a SSH public key and returns the path to the associated signed certificate.
@@ -81,14 +235,126 @@ func GetPublicKeyCertPath(pubKeyPath string) string {
 	return filepath.Join(filepath.Dir(pubKeyPath), newName)
 }
 
-// IsCertificateValid takes a SSH certificate and returns whether or not it is expired (TTL has been exceeded).
+// CheckCertificate takes a SSH certificate and returns an error describing why it can't be used with the given public
+// key. The certificate must be a user certificate issued for the given public key and, if the given CA public key is not
+// empty, signed by that CA. Both keys are expected in the authorized_keys format.
+func CheckCertificate(cert *cssh.Certificate, publicKey []byte, caKey []byte) error {
+	if cert.CertType != cssh.UserCert {
+		return fmt.Errorf("certificate is not a user certificate")
+	}
+
+	key, _, _, _, err := cssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("error parsing public key: %w", err)
+	}
+
+	if !bytes.Equal(cert.Key.Marshal(), key.Marshal()) {
+		return fmt.Errorf("certificate was issued for a different public key")
+	}
+
+	if len(caKey) == 0 {
+		return nil
+	}
+
+	ca, _, _, _, err := cssh.ParseAuthorizedKey(caKey)
+	if err != nil {
+		return fmt.Errorf("error parsing CA public key: %w", err)
+	}
+
+	if cert.SignatureKey == nil || !bytes.Equal(cert.SignatureKey.Marshal(), ca.Marshal()) {
+		return fmt.Errorf("certificate was not signed by the expected CA")
+	}
+
+	return nil
+}
+
+// CheckCertificateOptions takes a SSH certificate and returns an error describing why it differs from one signed with
+// the given options. Empty options are left to the role and match any certificate, otherwise the certificate must
+// include every given principal and have exactly the given key ID, extensions and critical options.
+func CheckCertificateOptions(cert *cssh.Certificate, principals []string, keyID string, extensions map[string]string,
+	criticalOptions map[string]string) error {
+	for _, principal := range principals {
+		if !HasPrincipal(cert, principal) {
+			return fmt.Errorf("certificate does not include principal %q", principal)
+		}
+	}
+
+	if keyID != "" && cert.KeyId != keyID {
+		return fmt.Errorf("certificate has key id %q instead of %q", cert.KeyId, keyID)
+	}
+
+	if len(extensions) > 0 && !equalOptions(cert.Extensions, extensions) {
+		return fmt.Errorf("certificate extensions differ from those requested")
+	}
+
+	if len(criticalOptions) > 0 && !equalOptions(cert.CriticalOptions, criticalOptions) {
+		return fmt.Errorf("certificate critical options differ from those requested")
+	}
+
+	return nil
+}
+
+// equalOptions returns whether the given certificate extensions or critical options are the same.
+func equalOptions(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsCertificateValid takes a SSH certificate and returns whether or not it is currently valid. It is equivalent to
+// IsCertificateValidFor without a minimum ttl or clock skew.
 func IsCertificateValid(cert *cssh.Certificate) bool {
-	validBefore := int64(cert.ValidBefore)
-	validAfter := int64(cert.ValidAfter)
-	now := time.Now().Unix()
-	if now < validBefore && now > validAfter {
+	return IsCertificateValidFor(cert, 0, 0)
+}
+
+// IsCertificateValidFor takes a SSH certificate and returns whether it remains valid for at least minTTL. The given
+// clock skew is tolerated when checking the certificate is already valid, since a freshly issued certificate may appear
+// to be from the future if the local clock is behind.
+func IsCertificateValidFor(cert *cssh.Certificate, minTTL time.Duration, skew time.Duration) bool {
+	now := time.Now()
+	if now.Add(skew).Before(time.Unix(int64(cert.ValidAfter), 0)) {
+		return false
+	}
+
+	// Certificates valid forever can't be converted to a time
+	if cert.ValidBefore == cssh.CertTimeInfinity {
 		return true
 	}
 
-	return false
-}
\ No newline at end of file
+	remaining := time.Unix(int64(cert.ValidBefore), 0).Sub(now)
+	return remaining > 0 && remaining >= minTTL
+}
+
+// GetMinTTL returns the minimum remaining lifetime to require of the given SSH certificate. The given value is either a
+// duration (i.e. 5m) or a percentage of the certificate's total lifetime (i.e. 20%). An empty value requires no minimum.
+func GetMinTTL(cert *cssh.Certificate, value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	if !strings.HasSuffix(value, "%") {
+		return time.ParseDuration(value)
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid percentage %q", value)
+	}
+
+	// Certificates valid forever have no meaningful lifetime to take a percentage of
+	if cert.ValidBefore == cssh.CertTimeInfinity || cert.ValidBefore <= cert.ValidAfter {
+		return 0, nil
+	}
+
+	lifetime := time.Duration(cert.ValidBefore-cert.ValidAfter) * time.Second
+	return time.Duration(float64(lifetime) * percent / 100), nil
+}
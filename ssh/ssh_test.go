@@ -1,9 +1,14 @@
 package ssh
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"github.com/stretchr/testify/assert"
 	cssh "golang.org/x/crypto/ssh"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -18,6 +23,77 @@ func TestNewSSHCommand(t *testing.T) {
 	assert.Equal(t, result.Stdout, os.Stdout)
 }
 
+func TestParseDestination(t *testing.T) {
+	tests := []struct {
+		destination string
+		user        string
+		host        string
+	}{
+		{"web1", "", "web1"},
+		{"deploy@web1.example.com", "deploy", "web1.example.com"},
+		{"jdoe@corp@web1", "jdoe@corp", "web1"},
+		{"ssh://deploy@web1:2222", "deploy", "web1"},
+		{"ssh://web1", "", "web1"},
+	}
+
+	for _, test := range tests {
+		user, host := ParseDestination(test.destination)
+		assert.Equal(t, test.user, user, test.destination)
+		assert.Equal(t, test.host, host, test.destination)
+	}
+}
+
+func TestGetRemoteUser(t *testing.T) {
+	if _, err := exec.LookPath("ssh"); err != nil {
+		t.Skip("ssh is not installed")
+	}
+
+	tempDir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := filepath.Join(tempDir, "config")
+	if err := ioutil.WriteFile(config, []byte("Host web1\n  User deploy\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		user string
+	}{
+		{[]string{"web1", "-F", config}, "deploy"},
+		{[]string{"admin@web1", "-F", config}, "admin"},
+		{[]string{"web2", "-F", config, "-l", "admin"}, "admin"},
+		{[]string{"web2", "-F", config}, ""},
+		{[]string{"web2", "-F", config, "-l", localUsername()}, localUsername()},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.user, GetRemoteUser(test.args), test.args)
+	}
+}
+
+func TestParseConfigUser(t *testing.T) {
+	output := []byte("host web1\nuser deploy\nport 22\n")
+	assert.Equal(t, "deploy", parseConfigUser(output))
+	assert.Equal(t, "", parseConfigUser([]byte("host web1\n")))
+}
+
+func TestParseRemoteUser(t *testing.T) {
+	assert.Equal(t, "deploy", parseRemoteUser([]string{"deploy@web1", "-l", "admin"}))
+	assert.Equal(t, "admin", parseRemoteUser([]string{"web1", "-L", "80:intra:80", "-l", "admin"}))
+	assert.Equal(t, "admin", parseRemoteUser([]string{"web1", "-ladmin"}))
+	assert.Equal(t, "", parseRemoteUser([]string{"web1", "uptime"}))
+}
+
+func TestHasPrincipal(t *testing.T) {
+	assert.True(t, HasPrincipal(&cssh.Certificate{ValidPrincipals: []string{"jdoe", "deploy"}}, "deploy"))
+	assert.False(t, HasPrincipal(&cssh.Certificate{ValidPrincipals: []string{"jdoe"}}, "deploy"))
+	assert.True(t, HasPrincipal(&cssh.Certificate{}, "deploy"))
+}
+
 func TestGetPublicKeyPath(t *testing.T) {
 	path := "some/fake/path/key"
 
@@ -37,6 +113,48 @@ func TestGetPublicKeyPath(t *testing.T) {
 	})
 }
 
+func TestFindIdentities(t *testing.T) {
+	home, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", oldHome)
+
+	sshDir := filepath.Join(home, ".ssh")
+	if err := os.MkdirAll(sshDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"id_rsa", "id_ecdsa_sk", "other"} {
+		if err := ioutil.WriteFile(filepath.Join(sshDir, name+".pub"), []byte("key"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("With default identities", func(t *testing.T) {
+		result, err := FindIdentities(DefaultIdentities)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{filepath.Join(sshDir, "id_ecdsa_sk"), filepath.Join(sshDir, "id_rsa")}, result)
+
+		pubKeyPath, err := GetPublicKeyPath("")
+		assert.Nil(t, err)
+		assert.Equal(t, filepath.Join(sshDir, "id_ecdsa_sk.pub"), pubKeyPath)
+	})
+	t.Run("With configured identities", func(t *testing.T) {
+		result, err := FindIdentities([]string{filepath.Join(sshDir, "other"), "id_rsa", "id_ed25519"})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{filepath.Join(sshDir, "other"), filepath.Join(sshDir, "id_rsa")}, result)
+	})
+	t.Run("Without any identities", func(t *testing.T) {
+		result, err := FindIdentities([]string{"id_ed25519"})
+		assert.Nil(t, err)
+		assert.Empty(t, result)
+	})
+}
+
 func TestGetPublicKeyCertPath(t *testing.T) {
 	path := "/home/user/.ssh/id_rsa.pub"
 	expected := "/home/user/.ssh/id_rsa-cert.pub"
@@ -44,6 +162,104 @@ func TestGetPublicKeyCertPath(t *testing.T) {
 	assert.Equal(t, expected, GetPublicKeyCertPath(path))
 }
 
+// newTestKey returns a new ed25519 signer along with its public key in the authorized_keys format.
+func newTestKey(t *testing.T) (cssh.Signer, []byte) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := cssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer, cssh.MarshalAuthorizedKey(signer.PublicKey())
+}
+
+func TestParseCertificate(t *testing.T) {
+	ca, _ := newTestKey(t)
+	user, userKey := newTestKey(t)
+	cert := &cssh.Certificate{Key: user.PublicKey(), CertType: cssh.UserCert, ValidBefore: cssh.CertTimeInfinity}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseCertificate(cssh.MarshalAuthorizedKey(cert))
+	assert.Nil(t, err)
+	assert.Equal(t, cert.Marshal(), result.Marshal())
+
+	_, err = ParseCertificate(userKey)
+	assert.NotNil(t, err)
+}
+
+func TestCheckCertificate(t *testing.T) {
+	ca, caKey := newTestKey(t)
+	user, userKey := newTestKey(t)
+	_, otherKey := newTestKey(t)
+	_, otherCAKey := newTestKey(t)
+
+	newCert := func(certType uint32) *cssh.Certificate {
+		cert := &cssh.Certificate{
+			Key:         user.PublicKey(),
+			CertType:    certType,
+			ValidBefore: cssh.CertTimeInfinity,
+		}
+		if err := cert.SignCert(rand.Reader, ca); err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	t.Run("With matching certificate", func(t *testing.T) {
+		assert.Nil(t, CheckCertificate(newCert(cssh.UserCert), userKey, caKey))
+	})
+	t.Run("Without CA public key", func(t *testing.T) {
+		assert.Nil(t, CheckCertificate(newCert(cssh.UserCert), userKey, nil))
+	})
+	t.Run("With different public key", func(t *testing.T) {
+		assert.NotNil(t, CheckCertificate(newCert(cssh.UserCert), otherKey, caKey))
+	})
+	t.Run("With host certificate", func(t *testing.T) {
+		assert.NotNil(t, CheckCertificate(newCert(cssh.HostCert), userKey, caKey))
+	})
+	t.Run("With different CA", func(t *testing.T) {
+		assert.NotNil(t, CheckCertificate(newCert(cssh.UserCert), userKey, otherCAKey))
+	})
+}
+
+func TestCheckCertificateOptions(t *testing.T) {
+	cert := &cssh.Certificate{
+		ValidPrincipals: []string{"jdoe", "deploy"},
+		KeyId:           "jdoe-laptop",
+		Permissions: cssh.Permissions{
+			Extensions:      map[string]string{"permit-pty": ""},
+			CriticalOptions: map[string]string{"source-address": "10.0.0.0/8"},
+		},
+	}
+
+	t.Run("With matching options", func(t *testing.T) {
+		assert.Nil(t, CheckCertificateOptions(cert, []string{"deploy"}, "jdoe-laptop", map[string]string{"permit-pty": ""},
+			map[string]string{"source-address": "10.0.0.0/8"}))
+	})
+	t.Run("Without options", func(t *testing.T) {
+		assert.Nil(t, CheckCertificateOptions(cert, nil, "", nil, nil))
+	})
+	t.Run("With missing principal", func(t *testing.T) {
+		assert.NotNil(t, CheckCertificateOptions(cert, []string{"root"}, "", nil, nil))
+	})
+	t.Run("With different key id", func(t *testing.T) {
+		assert.NotNil(t, CheckCertificateOptions(cert, nil, "ci", nil, nil))
+	})
+	t.Run("With additional extension", func(t *testing.T) {
+		assert.NotNil(t, CheckCertificateOptions(cert, nil, "", map[string]string{"permit-pty": "",
+			"permit-port-forwarding": ""}, nil))
+	})
+	t.Run("With different critical option", func(t *testing.T) {
+		assert.NotNil(t, CheckCertificateOptions(cert, nil, "", nil, map[string]string{"source-address": "192.168.0.0/16"}))
+	})
+}
+
 func TestIsCertificateValid(t *testing.T) {
 	t.Run("With valid time", func(t *testing.T) {
 		cert := &cssh.Certificate{
@@ -59,4 +275,61 @@ func TestIsCertificateValid(t *testing.T) {
 		}
 		assert.False(t, IsCertificateValid(cert))
 	})
-}
\ No newline at end of file
+}
+
+func TestIsCertificateValidFor(t *testing.T) {
+	now := uint64(time.Now().Unix())
+
+	t.Run("With enough remaining lifetime", func(t *testing.T) {
+		cert := &cssh.Certificate{ValidAfter: now - 60, ValidBefore: now + 600}
+		assert.True(t, IsCertificateValidFor(cert, 5*time.Minute, 0))
+	})
+	t.Run("With too little remaining lifetime", func(t *testing.T) {
+		cert := &cssh.Certificate{ValidAfter: now - 600, ValidBefore: now + 60}
+		assert.False(t, IsCertificateValidFor(cert, 5*time.Minute, 0))
+	})
+	t.Run("With expired certificate", func(t *testing.T) {
+		cert := &cssh.Certificate{ValidAfter: now - 600, ValidBefore: now - 60}
+		assert.False(t, IsCertificateValidFor(cert, 0, 0))
+	})
+	t.Run("With certificate from the future", func(t *testing.T) {
+		cert := &cssh.Certificate{ValidAfter: now + 30, ValidBefore: now + 600}
+		assert.False(t, IsCertificateValidFor(cert, 0, 0))
+		assert.True(t, IsCertificateValidFor(cert, 0, time.Minute))
+	})
+	t.Run("With certificate valid forever", func(t *testing.T) {
+		cert := &cssh.Certificate{ValidAfter: 0, ValidBefore: cssh.CertTimeInfinity}
+		assert.True(t, IsCertificateValidFor(cert, time.Hour, 0))
+	})
+}
+
+func TestGetMinTTL(t *testing.T) {
+	cert := &cssh.Certificate{ValidAfter: 1000, ValidBefore: 1000 + 3600}
+
+	t.Run("With duration", func(t *testing.T) {
+		result, err := GetMinTTL(cert, "5m")
+		assert.Nil(t, err)
+		assert.Equal(t, 5*time.Minute, result)
+	})
+	t.Run("With percentage", func(t *testing.T) {
+		result, err := GetMinTTL(cert, "25%")
+		assert.Nil(t, err)
+		assert.Equal(t, 15*time.Minute, result)
+	})
+	t.Run("With percentage of certificate valid forever", func(t *testing.T) {
+		result, err := GetMinTTL(&cssh.Certificate{ValidBefore: cssh.CertTimeInfinity}, "25%")
+		assert.Nil(t, err)
+		assert.Equal(t, time.Duration(0), result)
+	})
+	t.Run("With empty value", func(t *testing.T) {
+		result, err := GetMinTTL(cert, "")
+		assert.Nil(t, err)
+		assert.Equal(t, time.Duration(0), result)
+	})
+	t.Run("With invalid values", func(t *testing.T) {
+		_, err := GetMinTTL(cert, "150%")
+		assert.NotNil(t, err)
+		_, err = GetMinTTL(cert, "soon")
+		assert.NotNil(t, err)
+	})
+}
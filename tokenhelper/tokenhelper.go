@@ -0,0 +1,93 @@
+// The tokenhelper package provides access to Vault tokens through the token helper protocol used by the Vault CLI. This
+// allows vssh to share tokens with the Vault CLI, including when an external token helper is configured in ~/.vault.
+package tokenhelper
+
+import (
+	"github.com/hashicorp/vault/command/config"
+	"github.com/hashicorp/vault/command/token"
+	"github.com/jmgilman/vssh/internal/storage"
+	homedir "github.com/mitchellh/go-homedir"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NewHelper returns the token helper configured in the Vault CLI configuration file (~/.vault or $VAULT_CONFIG_PATH).
+// If no token helper is configured, an InternalHelper storing the token at ~/.vault-token is returned.
+func NewHelper() (token.TokenHelper, error) {
+	conf, err := config.LoadConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHelperWithPath(conf.TokenHelper)
+}
+
+// NewHelperWithPath returns an external token helper which executes the binary at the given path. If the path is
+// empty, an InternalHelper storing the token at ~/.vault-token is returned instead.
+func NewHelperWithPath(path string) (token.TokenHelper, error) {
+	if path == "" {
+		return NewInternalHelper()
+	}
+
+	path, err := token.ExternalTokenHelperPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token.ExternalTokenHelper{BinaryPath: path, Env: os.Environ()}, nil
+}
+
+// InternalHelper is a token helper which stores the token in a file on disk. It is compatible with the internal token
+// helper used by the Vault CLI, but the file is always written atomically and is only accessible by the current user.
+type InternalHelper struct {
+	path string
+}
+
+// NewInternalHelper returns a new InternalHelper configured to store the token at ~/.vault-token.
+func NewInternalHelper() (*InternalHelper, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewInternalHelperWithPath(filepath.Join(home, ".vault-token")), nil
+}
+
+// NewInternalHelperWithPath returns a new InternalHelper configured to store the token at the given path.
+func NewInternalHelperWithPath(path string) *InternalHelper {
+	return &InternalHelper{path: path}
+}
+
+// Path returns the path the token is stored at.
+func (i *InternalHelper) Path() string {
+	return i.path
+}
+
+// Get returns the stored token or an empty string if no token has been stored. A token file owned by another user is
+// refused and a warning is given if it is accessible by other users.
+func (i *InternalHelper) Get() (string, error) {
+	data, err := storage.ReadSecretFile(i.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Store stores the given token, replacing any previously stored token.
+func (i *InternalHelper) Store(input string) error {
+	return storage.WriteSecretFile(i.path, []byte(input))
+}
+
+// Erase removes the stored token. It is not an error if no token has been stored.
+func (i *InternalHelper) Erase() error {
+	if err := os.Remove(i.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package tokenhelper
+
+import (
+	"fmt"
+	"github.com/hashicorp/vault/command/token"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testHelper runs the get, store, and erase operations against the given token helper.
+func testHelper(t *testing.T, h token.TokenHelper) {
+	t.Helper()
+
+	result, err := h.Get()
+	assert.Nil(t, err)
+	assert.Empty(t, result)
+
+	assert.Nil(t, h.Store("token"))
+	result, err = h.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, "token", result)
+
+	assert.Nil(t, h.Erase())
+	result, err = h.Get()
+	assert.Nil(t, err)
+	assert.Empty(t, result)
+}
+
+// writeExternalHelper writes a shell script implementing the token helper protocol to the given directory and returns
+// its path.
+func writeExternalHelper(t *testing.T, dir string) string {
+	t.Helper()
+
+	tokenPath := filepath.Join(dir, "stored-token")
+	script := fmt.Sprintf(`#!/bin/sh
+case "$1" in
+	get) cat %[1]q 2>/dev/null || true ;;
+	store) cat > %[1]q ;;
+	erase) rm -f %[1]q ;;
+esac
+`, tokenPath)
+
+	helperPath := filepath.Join(dir, "helper")
+	if err := ioutil.WriteFile(helperPath, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	return helperPath
+}
+
+func TestInternalHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	helper := NewInternalHelperWithPath(filepath.Join(dir, ".vault-token"))
+	testHelper(t, helper)
+
+	// The token should only be readable by the owner
+	assert.Nil(t, helper.Store("token"))
+	info, err := os.Stat(helper.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestNewHelperWithPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("With external helper", func(t *testing.T) {
+		helper, err := NewHelperWithPath(writeExternalHelper(t, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.IsType(t, &token.ExternalTokenHelper{}, helper)
+		testHelper(t, helper)
+	})
+	t.Run("With missing external helper", func(t *testing.T) {
+		_, err := NewHelperWithPath(filepath.Join(dir, "missing"))
+		assert.NotNil(t, err)
+	})
+	t.Run("Without external helper", func(t *testing.T) {
+		helper, err := NewHelperWithPath("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.IsType(t, &InternalHelper{}, helper)
+	})
+}
+
+func TestNewHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "config.hcl")
+	config := fmt.Sprintf("token_helper = %q\n", writeExternalHelper(t, dir))
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Setenv("VAULT_CONFIG_PATH", configPath); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("VAULT_CONFIG_PATH")
+
+	helper, err := NewHelper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IsType(t, &token.ExternalTokenHelper{}, helper)
+}